@@ -0,0 +1,148 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+// An ArrayList is a MutableList backed by a slice that grows as needed. The zero value is an empty list ready for use.
+type ArrayList struct {
+	items   []T
+	version int // incremented whenever the list is modified, so iterators can detect changes
+}
+
+var _ MutableList = &ArrayList{}
+
+// Creates a new ArrayList with enough space to hold the given number of items before it needs to grow.
+func MakeArrayList(capacity int) *ArrayList {
+	return &ArrayList{items: make([]T, 0, capacity)}
+}
+
+// Adds an item to the end of the list.
+func (l *ArrayList) Add(item T) {
+	l.items = append(l.items, item)
+	l.version++
+}
+
+// Adds the items from a sequence to the end of the list.
+func (l *ArrayList) AddRange(items Sequence) {
+	l.items = AddToSlice(l.items, items).([]T)
+	l.version++
+}
+
+// Removes all items from the list.
+func (l *ArrayList) Clear() {
+	for i := range l.items { // clear the slots so we don't keep the items alive
+		l.items[i] = nil
+	}
+	l.items = l.items[:0]
+	l.version++
+}
+
+// Indicates whether the list contains the given item. Items are compared in the same way as MakeContainsComparer.
+func (l *ArrayList) Contains(item T) bool {
+	return l.IndexOf(item) >= 0
+}
+
+// Returns the number of items in the list.
+func (l *ArrayList) Count() int {
+	return len(l.items)
+}
+
+// Gets the item at a given index, and panics if the index is out of range.
+func (l *ArrayList) Get(index int) T {
+	return l.items[index]
+}
+
+// Gets the index of the given item, or -1 if the item doesn't exist. Items are compared in the same way as MakeContainsComparer.
+func (l *ArrayList) IndexOf(item T) int {
+	return TSequence(l.items).IndexOf(item)
+}
+
+// Gets the index of the first item equal to the given item according to the given equality function (e.g. DeepEqual), or -1 if no
+// such item exists.
+func (l *ArrayList) IndexOfP(item T, equal func(a, b T) bool) int {
+	return TSequence(l.items).IndexOfP(item, equal)
+}
+
+// Inserts an item at the given index, shifting the item at that index and all subsequent items up by one. The index may equal the
+// length of the list, in which case the item is added to the end. Panics if the index is out of range.
+func (l *ArrayList) Insert(index int, item T) {
+	if index < 0 || index > len(l.items) {
+		panic("index out of range")
+	}
+	l.items = append(l.items, nil)
+	copy(l.items[index+1:], l.items[index:])
+	l.items[index] = item
+	l.version++
+}
+
+func (l *ArrayList) Iterator() Iterator {
+	return &arrayListIterator{l, -1, l.version}
+}
+
+// Removes the item at the given index, shifting all subsequent items down by one. Panics if the index is out of range.
+func (l *ArrayList) RemoveAt(index int) {
+	l.RemoveRange(index, 1)
+}
+
+// Removes the given number of items starting at the given index. Panics if the range extends outside the list.
+func (l *ArrayList) RemoveRange(index, count int) {
+	if index < 0 || count < 0 || index+count > len(l.items) {
+		panic("index out of range")
+	}
+	if count != 0 {
+		n := copy(l.items[index:], l.items[index+count:])
+		for i := index + n; i < len(l.items); i++ { // clear the vacated slots so we don't keep the items alive
+			l.items[i] = nil
+		}
+		l.items = l.items[:index+n]
+		l.version++
+	}
+}
+
+// Sets the item at a given index, and panics if the index is out of range.
+func (l *ArrayList) Set(index int, item T) {
+	l.items[index] = item
+	l.version++
+}
+
+type arrayListIterator struct {
+	l              *ArrayList
+	index, version int
+}
+
+func (i *arrayListIterator) Current() T {
+	if i.version != i.l.version {
+		panic("collection was modified during iteration")
+	} else if i.index < 0 || i.index >= len(i.l.items) {
+		panic("Current called outside sequence")
+	}
+	return i.l.items[i.index]
+}
+
+func (i *arrayListIterator) Next() bool {
+	if i.version != i.l.version {
+		panic("collection was modified during iteration")
+	} else if i.index+1 < len(i.l.items) {
+		i.index++
+		return true
+	}
+	i.index = len(i.l.items)
+	return false
+}
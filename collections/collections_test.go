@@ -22,8 +22,11 @@ package collections
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -33,6 +36,63 @@ import (
 type MyIteratorFunc func() (T, bool)
 type MySequenceFunc func() IteratorFunc
 
+func TestArrayList(t *testing.T) {
+	t.Parallel()
+
+	var l ArrayList // test the zero value
+	l.Add(1)
+	l.AddRange(toSequence([]int{2, 3, 4}))
+	l.Insert(0, 0)
+	l.Insert(5, 5)
+	l.Insert(3, "x")
+	assertListEqual(t, &l, 0, 1, 2, "x", 3, 4, 5)
+	assertSeqEqual(t, &l, 0, 1, 2, "x", 3, 4, 5)
+	assertEqual(t, l.IndexOf("x"), 3)
+	assertTrue(t, l.Contains(5), "l.Contains(5)")
+	assertFalse(t, l.Contains(6), "l.Contains(6)")
+	l.RemoveAt(3)
+	l.RemoveRange(4, 2)
+	l.RemoveRange(0, 0)
+	l.Set(0, -1)
+	assertListEqual(t, &l, -1, 1, 2, 3)
+	assertPanic(t, func() { l.Insert(5, 0) }, "out of range")
+	assertPanic(t, func() { l.RemoveRange(3, 2) }, "out of range")
+	assertPanic(t, func() { l.RemoveAt(-1) }, "out of range")
+	i := l.Iterator()
+	i.Next()
+	l.Add(4)
+	assertPanic(t, func() { i.Current() }, "modified")
+	l.Clear()
+	assertEqual(t, l.Count(), 0)
+	assertSeqEqual(t, &l)
+	assertEqual(t, MakeArrayList(10).Count(), 0)
+
+	// test wrapping pointers to slices as mutable lists
+	slice := []int{1, 2, 3}
+	ml, err := ToList(&slice)
+	assertTrue(t, err == nil, "ToList(&slice)")
+	ml.(MutableList).Add(4)
+	ml.(MutableList).Insert(0, 0)
+	ml.(MutableList).RemoveAt(2)
+	ml.(MutableList).AddRange(toSequence([]int{5, 6}))
+	ml.Set(1, 7)
+	assertSlicesEqual(t, ToSlice(toSequence(slice)), 0, 7, 3, 4, 5, 6)
+	assertListEqual(t, ml, 0, 7, 3, 4, 5, 6)
+	assertEqual(t, ml.IndexOf(4), 3)
+	assertPanic(t, func() { ml.(MutableList).RemoveRange(5, 2) }, "out of range")
+	assertPanic(t, func() { ml.(MutableList).Insert(7, 0) }, "out of range")
+	ml.(MutableList).Clear()
+	assertEqual(t, len(slice), 0)
+	ps := []*int{}
+	s, err := ToSequence(&ps)
+	s.(MutableList).Add(nil) // test that nil is converted to the zero value
+	assertEqual(t, len(ps), 1)
+	assertTrue(t, ps[0] == nil, "ps[0] == nil")
+	var np *[]int
+	_, err = ToList(np)
+	assertTrue(t, err != nil, "ToList(nil *[]int)")
+}
+
 func TestConverters(t *testing.T) {
 	t.Parallel()
 
@@ -46,6 +106,57 @@ func TestConverters(t *testing.T) {
 	s = MakeFunctionSequence(seqf)
 	assertSeqEqual(t, s, 1, 2, 3, 4, 5)
 
+	// test closable function sequences
+	closes := 0
+	s = MakeClosableFunctionSequence(func() (IteratorFunc, func() error) {
+		return rangef(3), func() error { closes++; return fmt.Errorf("closed %d", closes) }
+	})
+	assertSlicesEqual(t, ToSlice(s), 1, 2, 3)
+	assertEqual(t, closes, 1)
+	i := s.Iterator()
+	assertTrue(t, i.Next(), "i.Next()")
+	assertEqual(t, CloseIterator(i).Error(), "closed 2")
+	assertEqual(t, CloseIterator(i).Error(), "closed 2") // closing again returns the same error
+	assertEqual(t, closes, 2)
+	assertFalse(t, i.Next(), "i.Next() after Close")
+	assertPanic(t, func() { i.Current() }, "Current called outside sequence")
+	i = s.Iterator()
+	for i.Next() {
+	}
+	assertEqual(t, closes, 3) // exhausting the iterator closes it
+	assertEqual(t, CloseIterator(i).Error(), "closed 3")
+	assertEqual(t, CloseIterator(MakeFunctionSequence(seqf).Iterator()), nil)
+
+	// test fallible function sequences
+	failure, closes := fmt.Errorf("read failed"), 0
+	s = MakeFallibleFunctionSequence(func() (FallibleIteratorFunc, func() error) {
+		next := rangef(2)
+		tryNext := func() (T, bool, error) {
+			if item, ok := next(); ok {
+				return item, true, nil
+			}
+			return nil, false, failure
+		}
+		return tryNext, func() error { closes++; return nil }
+	})
+	i = s.Iterator()
+	assertTrue(t, i.Next() && i.Next(), "i.Next()")
+	assertEqual(t, IteratorError(i), nil)
+	assertFalse(t, i.Next(), "i.Next() after failure")
+	assertEqual(t, IteratorError(i), failure)
+	assertEqual(t, closes, 1) // failing closes the iterator
+	assertEqual(t, FinishIterator(i), failure)
+	items, err := TryToSlice(s)
+	assertTrue(t, items == nil && err == failure, "TryToSlice fails")
+	assertPanic(t, func() { ToSlice(s) }, "read failed")
+	assertPanic(t, func() { AddToSlice([]int(nil), s) }, "read failed")
+	assertPanic(t, func() { MakeHashSet(0).UnionWith(s) }, "read failed")
+	assertPanic(t, func() { MakeHashSet(0).ExceptWith(s) }, "read failed")
+	items, err = TryToSlice(MakeFunctionSequence(seqf))
+	assertSlicesEqual(t, items, 1, 2, 3, 4, 5)
+	assertEqual(t, err, nil)
+	assertEqual(t, IteratorError(MakeFunctionSequence(seqf).Iterator()), nil)
+
 	// test ToDictionary
 	d, err := ToDictionary(nil)
 	assertTrue(t, d == nil, "nil d is nil")
@@ -154,6 +265,66 @@ func TestConverters(t *testing.T) {
 	assertFalse(t, ok, "d.TryGet(2)")
 }
 
+func TestDeepEqual(t *testing.T) {
+	t.Parallel()
+
+	type node struct {
+		Next  *node
+		Value int
+		tags  []string
+	}
+	a1, b1 := &node{Value: 1}, &node{Value: 1}
+	a1.Next, b1.Next = a1, &node{Value: 1, Next: b1} // a1 -> a1, b1 -> b1' -> b1
+	a2 := &node{Value: 1, tags: []string{"x"}}
+	a2.Next = a2
+
+	equal := []T{[]int{1, 2}, []int{1, 2}, map[string][]int{"a": {1}}, map[string][]int{"a": {1}}, Pair{1, []T{"x", []int{2}}},
+		Pair{1, []T{"x", []int{2}}}, a1, b1, node{a1, 1, []string{"y"}}, node{b1, 1, []string{"y"}}, [2][]int{{1}, {2}},
+		[2][]int{{1}, {2}}, version{1, 2, "a"}, version{1, 2, "b"}, []version{{1, 2, "a"}}, []version{{1, 2, "b"}}}
+	for i := 0; i < len(equal); i += 2 {
+		a, b := equal[i], equal[i+1]
+		assertTrue(t, DeepEqual(a, b), fmt.Sprintf("deep(%v, %v)", a, b))
+		assertTrue(t, DeepEqual(b, a), fmt.Sprintf("deep(%v, %v)", b, a))
+		assertEqual(t, DeepHash(a), DeepHash(b))
+	}
+	assertTrue(t, DeepEqual(nil, nil), "deep(nil, nil)")
+	assertEqual(t, DeepHash(nil), uint64(0))
+
+	unequal := []T{[]int{1, 2}, []int{1, 3}, []int{1, 2}, []int{1, 2, 3}, []int{}, []int(nil), map[string]int{"a": 1},
+		map[string]int{"a": 2}, map[string]int{"a": 1}, map[string]int{"b": 1}, Pair{1, []int{2}}, Pair{1, []int32{2}}, a1, a2,
+		[]int{1}, []int32{1}, &node{Value: 1}, (*node)(nil), []version{{1, 2, "a"}}, []version{{1, 3, "a"}}}
+	for i := 0; i < len(unequal); i += 2 {
+		a, b := unequal[i], unequal[i+1]
+		assertFalse(t, DeepEqual(a, b), fmt.Sprintf("deep(%v, %v)", a, b))
+		assertFalse(t, DeepEqual(b, a), fmt.Sprintf("deep(%v, %v)", b, a))
+	}
+	f := func() {}
+	assertTrue(t, DeepEqual([]T{f}, []T{f}), "deep([f], [f])")
+	assertFalse(t, DeepEqual([]T{f}, []T{func() {}}), "deep([f], [g])")
+
+	// test deep equality in sets and lists
+	s := MakeHashSetWithComparer(0, DeepEqual, DeepHash)
+	s.UnionWith(TSequence{[]int{1, 2}, []int{1, 2}, []int{2, 1}, Pair{1, []int{1}}, Pair{1, []int{1}}, a1, b1})
+	assertEqual(t, s.Count(), 4)
+	assertTrue(t, s.Contains([]int{2, 1}), "s.Contains([2,1])")
+	s.IntersectWith(TSequence{[]int{1, 2}, a1})
+	assertEqual(t, s.Count(), 2)
+	s = MakeHashSetWithComparer(0, DeepEqual, nil) // test a missing hash function
+	s.UnionWith(TSequence{[]int{1, 2}, []int{1, 2}, []int{2, 1}})
+	assertEqual(t, s.Count(), 2)
+
+	items := [][]int{{1}, {2, 3}}
+	assertEqual(t, TSequence{0, []int{1}, []int{2, 3}}.IndexOf([]int{2, 3}), -1)
+	assertEqual(t, TSequence{0, []int{1}, []int{2, 3}}.IndexOfP([]int{2, 3}, DeepEqual), 2)
+	assertEqual(t, IntSequence{1, 2, 3}.IndexOfP(3, DeepEqual), 2)
+	assertEqual(t, IntSequence{1, 2, 3}.IndexOfP(4, DeepEqual), -1)
+	assertEqual(t, toSequence(items).(genericArraySequence).IndexOfP([]int{2, 3}, DeepEqual), 1)
+	l := MakeArrayList(0)
+	l.Add([]int{1})
+	assertEqual(t, l.IndexOfP([]int{1}, DeepEqual), 0)
+	assertEqual(t, l.IndexOfP([]int{1}, GenericEqual), -1)
+}
+
 func TestEquals(t *testing.T) {
 	t.Parallel()
 
@@ -208,6 +379,142 @@ func TestEquals(t *testing.T) {
 
 	assertTrue(t, MakeContainsComparer(nil)(p), "nil c= *int(0)")
 	assertFalse(t, MakeContainsComparer(p)(nil), "*int(0) c= p")
+
+	type S struct { // a struct with incomparable fields, which == can't compare
+		A []int
+		B T
+		_ int
+	}
+	s1, s2, s3 := S{A: slice, B: []T{f}}, S{A: slice, B: "x"}, [2]T{slice, Pair{1, slice}}
+	assertTrue(t, GenericEqual(s1, S{A: slice, B: s1.B}), "equal(s1, copy of s1)")
+	assertFalse(t, GenericEqual(s1, s2), "equal(s1, s2)")
+	assertFalse(t, GenericEqual(s2, S{A: []int{1, 2}, B: "x"}), "equal(s2, s2 with a different slice)")
+	assertTrue(t, GenericEqual(s3, [2]T{slice, Pair{1, slice}}), "equal(s3, copy of s3)")
+	assertFalse(t, GenericEqual(s3, [2]T{slice, Pair{1, []int{1, 2}}}), "equal(s3, s3 with a different slice)")
+	assertFalse(t, GenericEqual(s3, [2]T{slice, Pair{int8(1), slice}}), "equal(s3, s3 with a different key type)")
+	s, _ = ToList([]T{s1, s2})
+	assertTrue(t, s.Contains(S{A: slice, B: "x"}), "Contains(s2)")
+	assertFalse(t, s.Contains(S{A: nil, B: "x"}), "Contains(s2 without a slice)")
+
+	for _, v := range []T{nil, 0, -1, int8(-1), uint(5), 3.14, "hello", true, p, &m, slice, f, map[T]T{}, Pair{1, 2}, Pair{"f", f}, s1,
+		s2, s3, [3]int{1, 2, 3}, complex(1, 2)} {
+		assertTrue(t, GenericEqual(v, v), fmt.Sprintf("equal(%v, %v)", v, v))
+		assertEqual(t, GenericHash(v), GenericHash(v))
+	}
+	assertEqual(t, GenericHash(Pair{"f", f}), GenericHash(Pair{"f", f}))
+	assertEqual(t, GenericHash(s1), GenericHash(S{A: slice, B: s1.B}))
+	assertEqual(t, GenericHash(0.0), GenericHash(math.Copysign(0, -1))) // 0 == -0 so they must hash the same
+	assertTrue(t, GenericHash(Pair{1, 2}) != GenericHash(Pair{2, 1}), "hash(pair(1,2)) == hash(pair(2,1))")
+	assertTrue(t, GenericHash("a") != GenericHash("b"), "hash(a) == hash(b)")
+
+	v1, v2, v3 := version{1, 2, "a"}, version{1, 2, "b"}, version{1, 3, "a"} // test Equatable
+	assertTrue(t, GenericEqual(v1, v2), "equal(v1, v2)")
+	assertFalse(t, GenericEqual(v1, v3), "equal(v1, v3)")
+	assertEqual(t, GenericHash(v1), GenericHash(v2))
+	assertTrue(t, GenericEqual(Pair{v1, 1}, Pair{v2, 1}), "equal(pair(v1,1), pair(v2,1))")
+	assertEqual(t, GenericHash(Pair{v1, 1}), GenericHash(Pair{v2, 1}))
+	assertTrue(t, GenericEqual([1]version{v1}, [1]version{v2}), "equal([v1], [v2])")
+	assertEqual(t, GenericHash([1]version{v1}), GenericHash([1]version{v2}))
+	assertFalse(t, GenericEqual(v1, &v1), "equal(v1, &v1)")
+	s, _ = ToList([]T{v1, Pair{v1, 1}})
+	assertTrue(t, s.Contains(v2), "Contains(v2)")
+	assertTrue(t, s.Contains(Pair{v2, 1}), "Contains(pair(v2,1))")
+	assertFalse(t, s.Contains(v3), "Contains(v3)")
+	hs := MakeHashSetFrom(TSequence{v1, v2, v3, [1]version{v1}, [1]version{v2}})
+	assertEqual(t, hs.Count(), 3)
+}
+
+func TestHashSet(t *testing.T) {
+	t.Parallel()
+
+	var s HashSet // test the zero value
+	assertSeqEqual(t, &s)
+	assertTrue(t, s.IsSubsetOf(TSequence{1}), "{} <= {1}")
+	assertFalse(t, s.Overlaps(TSequence{1}), "{} overlaps {1}")
+	assertTrue(t, s.Add(1), "s.Add(1)")
+	assertFalse(t, s.Add(1), "s.Add(1) again")
+	assertTrue(t, s.Add("a"), "s.Add(a)")
+	assertSetEqual(t, &s, 1, "a")
+	assertTrue(t, s.Remove("a"), "s.Remove(a)")
+	assertFalse(t, s.Remove("a"), "s.Remove(a) again")
+	assertSetEqual(t, &s, 1)
+
+	s.UnionWith(TSequence{1, 2, 3, 3, 4})
+	assertSetEqual(t, &s, 1, 2, 3, 4)
+	s.IntersectWith(TSequence{0, 2, 4, 4, 6})
+	assertSetEqual(t, &s, 2, 4)
+	s.SymmetricExceptWith(TSequence{1, 2, 3, 3})
+	assertSetEqual(t, &s, 1, 3, 4)
+	s.ExceptWith(TSequence{3, 5, 3})
+	assertSetEqual(t, &s, 1, 4)
+
+	assertTrue(t, s.IsSubsetOf(TSequence{4, 1, 1}), "{1,4} <= {4,1,1}")
+	assertTrue(t, s.IsSubsetOf(TSequence{0, 1, 2, 3, 4}), "{1,4} <= {0,1,2,3,4}")
+	assertFalse(t, s.IsSubsetOf(TSequence{1, 1, 2}), "{1,4} <= {1,1,2}")
+	assertTrue(t, s.IsSupersetOf(TSequence{4, 4}), "{1,4} >= {4,4}")
+	assertTrue(t, s.IsSupersetOf(TSequence{}), "{1,4} >= {}")
+	assertFalse(t, s.IsSupersetOf(TSequence{1, 2}), "{1,4} >= {1,2}")
+	assertTrue(t, s.Overlaps(TSequence{2, 3, 4}), "{1,4} overlaps {2,3,4}")
+	assertFalse(t, s.Overlaps(TSequence{2, 3}), "{1,4} overlaps {2,3}")
+
+	o := MakeHashSetFrom(TSequence{4, 5, 5})
+	assertSetEqual(t, o, 4, 5)
+	s.SymmetricExceptWith(o)
+	assertSetEqual(t, &s, 1, 5)
+	assertSetEqual(t, o, 4, 5) // the argument is unchanged
+
+	s.ExceptWith(&s)
+	assertSetEqual(t, &s)
+	s.UnionWith(TSequence{1, 5})
+	s.SymmetricExceptWith(&s)
+	assertSetEqual(t, &s)
+	slice := []int{1}
+	s.UnionWith(TSequence{slice, Pair{1, slice}, []int{1}, Pair{1, slice}, Pair{[]int{1}, 2}, 1, 1.5, 1, "a"}) // test unhashable items
+	assertTrue(t, s.IsSupersetOf(TSequence{slice, Pair{1, slice}, 1, 1.5, "a"}), "s >= {slice, [1,slice], 1, 1.5, a}")
+	assertEqual(t, s.Count(), 7) // the []int{1} slices are different slices and the Pairs have different keys
+	assertTrue(t, s.Remove(Pair{1, slice}), "s.Remove([1,slice])")
+	assertTrue(t, s.Remove(slice), "s.Remove(slice)")
+	s.IntersectWith(TSequence{"a", 1.5, 2})
+	assertSetEqual(t, &s, "a", 1.5)
+	s.UnionWith(TSequence{1, 5})
+
+	i := s.Iterator()
+	i.Next()
+	s.Add(6)
+	assertPanic(t, func() { i.Next() }, "modified")
+	i = s.Iterator()
+	assertPanic(t, func() { i.Current() }, "outside sequence")
+	for i.Next() {
+	}
+	assertFalse(t, i.Next(), "i.Next() after exhaustion")
+	assertPanic(t, func() { i.Current() }, "outside sequence")
+
+	s.Clear()
+	assertEqual(t, s.Count(), 0)
+	assertSeqEqual(t, &s)
+	assertEqual(t, MakeHashSet(10).Count(), 0)
+
+	// do random operations on both hashable and unhashable items and compare the results against a map
+	rand := rand.New(rand.NewSource(0))
+	m := make(map[int]bool)
+	for n := 0; n < 5000; n++ {
+		k := rand.Intn(200)
+		var item T = k
+		if k&1 != 0 {
+			item = Pair{k, nil} // Pairs aren't indexed with a go map, so this exercises the hash buckets
+		}
+		if rand.Intn(3) == 0 {
+			assertEqual(t, s.Remove(item), m[k])
+			delete(m, k)
+		} else {
+			assertEqual(t, s.Add(item), !m[k])
+			m[k] = true
+		}
+	}
+	assertEqual(t, s.Count(), len(m))
+	for k := range m {
+		assertTrue(t, s.Contains(k) || s.Contains(Pair{k, nil}), "s.Contains("+strconv.Itoa(k)+")")
+	}
 }
 
 func TestOrder(t *testing.T) {
@@ -229,13 +536,311 @@ func TestOrder(t *testing.T) {
 
 	assertFalse(t, GenericLessThan(Pair{1, 2}, 5), "Pair < 5")
 	assertTrue(t, GenericLessThan(5, Pair{1, 2}), "5 < Pair")
-	assertPanic(t, func() { GenericLessThan(Pair{1, 2}, Pair{1, 2}) }, "not comparable")
+	assertTrue(t, GenericLessThan(Pair{1, 2}, Pair{1, 3}), "Pair{1,2} < Pair{1,3}")
+	assertFalse(t, GenericLessThan(Pair{1, 2}, Pair{1, 2}), "Pair{1,2} < Pair{1,2}")
+	assertFalse(t, GenericLessThan(Pair{2, 0}, Pair{1, 3}), "Pair{2,0} < Pair{1,3}")
+
+	// test structural comparison of arrays and structs
+	assertTrue(t, GenericLessThan([2]int{1, 2}, [2]int{1, 3}), "[1,2] < [1,3]")
+	assertFalse(t, GenericLessThan([2]int{1, 3}, [2]int{1, 2}), "[1,3] < [1,2]")
+	assertPanic(t, func() { GenericLessThan([1]int{1}, [2]int{1, 0}) }, "not comparable") // arrays must have the same type
+	assertPanic(t, func() { GenericLessThan([2]int{1, 2}, [2]int8{1, 3}) }, "not comparable")
+	assertTrue(t, GenericLessThan([2]T{"a", 2}, [2]T{"b", 1}), "[a,2] < [b,1]")
+	type key struct {
+		Region string
+		Day    time.Time
+		secret int
+	}
+	now := time.Now()
+	keys := TSequence{key{"b", now, 1}, key{"a", now.Add(time.Hour), 0}, key{"a", now, 2}, key{"b", now.Add(-time.Hour), 3}}
+	sort.Sort(keys)
+	assertSeqEqual(t, keys, key{"a", now, 2}, key{"a", now.Add(time.Hour), 0}, key{"b", now.Add(-time.Hour), 3}, key{"b", now, 1})
+	assertFalse(t, GenericLessThan(key{"a", now, 1}, key{"a", now, 0}), "unexported fields are ignored")
+	type tagged struct {
+		A int `linq:"order=2,desc"`
+		B int
+		C int `linq:"order=1"`
+		D int `linq:"-"`
+	}
+	tags := TSequence{tagged{1, 1, 1, 0}, tagged{2, 1, 1, 0}, tagged{1, 0, 0, 0}, tagged{1, 0, 1, 1}, tagged{1, 0, 1, 0}}
+	sort.Stable(tags)
+	assertSeqEqual(t, tags, tagged{1, 0, 0, 0}, tagged{2, 1, 1, 0}, tagged{1, 0, 1, 1}, tagged{1, 0, 1, 0}, tagged{1, 1, 1, 0})
+	type opaque struct{ a int }
+	assertPanic(t, func() { GenericLessThan(opaque{1}, opaque{2}) }, "not comparable")
+	assertPanic(t, func() { GenericLessThan(key{}, tagged{}) }, "not comparable")
+	type bad struct {
+		A int `linq:"order=x"`
+	}
+	assertPanic(t, func() { GenericLessThan(bad{1}, bad{2}) }, "invalid order")
+	type bad2 struct {
+		A int `linq:"up"`
+	}
+	assertPanic(t, func() { GenericLessThan(bad2{1}, bad2{2}) }, "invalid option")
+
+	vs := TSequence{version{2, 0, ""}, version{1, 10, ""}, version{1, 2, ""}} // test Comparable
+	sort.Sort(vs)
+	assertSeqEqual(t, vs, version{1, 2, ""}, version{1, 10, ""}, version{2, 0, ""})
+	assertFalse(t, GenericLessThan(version{1, 2, "a"}, version{1, 2, "b"}), "v1.2a < v1.2b")
+	assertTrue(t, GenericLessThan(5, version{}), "5 < version") // values of different types are still ordered by kind
+
+	type age int8 // test named types
+	type name string
+	type flag bool
+	assertTrue(t, GenericLessThan(age(3), age(5)), "age 3 < age 5")
+	assertFalse(t, GenericLessThan(age(5), age(3)), "age 5 < age 3")
+	assertTrue(t, GenericLessThan(age(3), 4.5), "age 3 < 4.5")
+	assertTrue(t, GenericLessThan(uint(2), age(3)), "2u < age 3")
+	assertTrue(t, GenericLessThan(name("a"), name("b")), "name a < name b")
+	assertTrue(t, GenericLessThan(name("a"), "b"), "name a < b")
+	assertTrue(t, GenericLessThan(flag(false), flag(true)), "flag false < flag true")
+	ns := TSequence{name("c"), name("a"), name("b")}
+	sort.Sort(ns)
+	assertSeqEqual(t, ns, name("a"), name("b"), name("c"))
+	v, named := toBuiltinType(age(3))
+	assertEqual(t, v, int8(3))
+	assertTrue(t, named, "age is named")
+	v, named = toBuiltinType(flag(true))
+	assertEqual(t, v, true)
+	assertTrue(t, named, "flag is named")
+	v, named = toBuiltinType(3)
+	assertEqual(t, v, 3)
+	assertFalse(t, named, "int is named")
+}
+
+func TestOrderedDictionary(t *testing.T) {
+	t.Parallel()
+
+	var d OrderedDictionary // test the zero value
+	assertSeqEqual(t, &d)
+	for _, k := range []string{"c", "a", "d", "b"} {
+		d.Set(k, len(k))
+	}
+	d.Set("a", 42) // setting an existing key doesn't change its position
+	assertSeqEqual(t, &d, Pair{"c", 1}, Pair{"a", 42}, Pair{"d", 1}, Pair{"b", 1})
+	assertSeqEqual(t, d.Keys(), "c", "a", "d", "b")
+	assertDictionaryEqual(t, &d, "a", 42, "b", 1, "c", 1, "d", 1)
+	assertTrue(t, d.Contains(Pair{"a", 42}), "d.Contains([a,42])")
+	assertFalse(t, d.Contains(Pair{"a", 1}), "d.Contains([a,1])")
+	assertTrue(t, d.ContainsKey("d"), "d.ContainsKey(d)")
+	assertFalse(t, d.ContainsKey("e"), "d.ContainsKey(e)")
+	assertPanic(t, func() { d.Get("e") }, "not in map")
+
+	d.Remove("a")
+	d.Remove("e")
+	d.Set("a", 0) // removing and re-adding a key moves it to the end
+	assertSeqEqual(t, &d, Pair{"c", 1}, Pair{"d", 1}, Pair{"b", 1}, Pair{"a", 0})
+	i := d.Iterator()
+	i.Next()
+	d.Remove("c")
+	assertPanic(t, func() { i.Next() }, "modified")
+	assertPanic(t, func() { d.Iterator().Current() }, "outside sequence")
+
+	d.Clear()
+	assertEqual(t, d.Count(), 0)
+	assertSeqEqual(t, &d)
+	d2 := MakeOrderedDictionary(10)
+	d2.Set(2, "two")
+	d2.Set(1, "one")
+	assertSeqEqual(t, d2, Pair{2, "two"}, Pair{1, "one"})
+
+	slice := []int{1}
+	d2.Set(slice, 3) // keys that can't be used in go maps are supported
+	d2.Set(Pair{1, slice}, 4)
+	d2.Set(Pair{1, slice}, 5)
+	assertEqual(t, d2.Get(slice), 3)
+	assertFalse(t, d2.ContainsKey([]int{1}), "d2.ContainsKey([1])")
+	d2.Remove(2)
+	assertSeqEqual(t, d2, Pair{1, "one"}, Pair{slice, 3}, Pair{Pair{1, slice}, 5})
+}
+
+func TestPriorityQueue(t *testing.T) {
+	t.Parallel()
+
+	// test the zero value, which is a min-first queue using the generic comparer
+	var q PriorityQueue
+	_, ok := q.TryDequeue()
+	assertFalse(t, ok, "empty q.TryDequeue")
+	assertPanic(t, func() { q.Dequeue() }, "empty")
+	assertPanic(t, func() { q.Peek() }, "empty")
+	for _, v := range []T{5, 3.5, int8(9), 1, uint(7), 2} {
+		q.Enqueue(v)
+	}
+	assertEqual(t, q.Count(), 6)
+	assertTrue(t, q.Contains(3.5), "q.Contains(3.5)")
+	assertFalse(t, q.Contains(3), "q.Contains(3)")
+	assertEqual(t, q.Peek(), 1)
+	assertSlicesEqual(t, drain(&q), 1, 2, 3.5, 5, uint(7), int8(9))
+
+	// test heapifying a sequence in max-first mode with a custom comparer
+	strlen := func(a, b T) bool { return len(a.(string)) < len(b.(string)) }
+	q2 := MakePriorityQueueFrom(toSequence([]string{"ccc", "a", "dddd", "bb", "eeeee"}), strlen, true)
+	assertEqual(t, q2.Dequeue(), "eeeee")
+	q2.EnqueueRange(toSequence([]string{"ffffff", ""}))
+	v, ok := q2.TryPeek()
+	assertTrue(t, ok && v == "ffffff", "q2.TryPeek")
+	assertSlicesEqual(t, drain(q2), "ffffff", "dddd", "ccc", "bb", "a", "")
+
+	// test updating and removing items by handle
+	q3 := MakePriorityQueue(nil, false)
+	handles := make([]*PriorityQueueItem, 10)
+	for i := range handles {
+		handles[i] = q3.Add(i * 10)
+	}
+	assertEqual(t, handles[4].Value(), 40)
+	q3.Update(handles[9], -1) // move an item to the front
+	q3.Update(handles[0], 55) // and another one toward the back
+	assertTrue(t, q3.Remove(handles[3]), "q3.Remove(30)")
+	assertFalse(t, q3.Remove(handles[3]), "q3.Remove(30) again")
+	assertFalse(t, q3.Remove(nil), "q3.Remove(nil)")
+	assertEqual(t, q3.Dequeue(), -1)
+	assertFalse(t, q3.Remove(handles[9]), "q3.Remove(dequeued)")
+	assertPanic(t, func() { q3.Update(handles[9], 0) }, "not in the queue")
+	assertSlicesEqual(t, drain(q3), 10, 20, 40, 50, 55, 60, 70, 80)
+
+	// test iteration and clearing
+	q3.EnqueueRange(toSequence([]int{3, 1, 2}))
+	n := 0
+	for i := q3.Iterator(); i.Next(); {
+		n += i.Current().(int)
+	}
+	assertEqual(t, n, 6)
+	i := q3.Iterator()
+	i.Next()
+	q3.Enqueue(4)
+	assertPanic(t, func() { i.Next() }, "modified")
+	q3.Clear()
+	assertEqual(t, q3.Count(), 0)
+	assertSeqEqual(t, q3)
+
+	q3.EnqueueRange(toSequence([]int{2, 1}))
+	q3.EnqueueRange(q3) // test adding the queue to itself
+	assertSlicesEqual(t, drain(q3), 1, 1, 2, 2)
+}
+
+func TestQueues(t *testing.T) {
+	t.Parallel()
+
+	// test the FIFO queue, including wrapping around and growing the circular buffer
+	q := MakeArrayQueue(4)
+	_, ok := q.TryDequeue()
+	assertFalse(t, ok, "empty q.TryDequeue")
+	assertPanic(t, func() { q.Dequeue() }, "empty")
+	assertPanic(t, func() { q.Peek() }, "empty")
+	for i := 0; i < 3; i++ {
+		q.Enqueue(i)
+	}
+	assertEqual(t, q.Dequeue(), 0)
+	assertEqual(t, q.Dequeue(), 1)
+	for i := 3; i < 10; i++ {
+		q.Enqueue(i)
+	}
+	assertSeqEqual(t, q, 2, 3, 4, 5, 6, 7, 8, 9)
+	assertEqual(t, q.Count(), 8)
+	assertEqual(t, q.Peek(), 2)
+	v, ok := q.TryPeek()
+	assertTrue(t, ok && v == 2, "q.TryPeek")
+	assertTrue(t, q.Contains(9), "q.Contains(9)")
+	assertFalse(t, q.Contains(int8(9)), "q.Contains(int8 9)")
+	q.Clear()
+	assertEqual(t, q.Count(), 0)
+	assertSeqEqual(t, q)
+
+	// test that the zero value works, and that Contains matches nil against zero pointers
+	var p *int
+	var zq ArrayQueue
+	zq.Enqueue(p)
+	assertTrue(t, zq.Contains(nil), "zq.Contains(nil)")
+	assertPanic(t, func() { MakeArrayQueue(-1) }, "non-negative")
+
+	// test the stack
+	var s Stack
+	for i := 0; i < 10; i++ {
+		s.Push(i)
+	}
+	assertSeqEqual(t, &s, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0)
+	assertEqual(t, s.Pop(), 9)
+	assertEqual(t, s.Dequeue(), 8)
+	assertEqual(t, s.Peek(), 7)
+	s.Enqueue(42)
+	v, ok = s.TryPop()
+	assertTrue(t, ok && v == 42, "s.TryPop")
+	v, ok = s.TryPeek()
+	assertTrue(t, ok && v == 7, "s.TryPeek")
+	assertTrue(t, s.Contains(0), "s.Contains(0)")
+	assertFalse(t, s.Contains(8), "s.Contains(8)")
+	s.Clear()
+	_, ok = s.TryPop()
+	assertFalse(t, ok, "empty s.TryPop")
+	assertPanic(t, func() { s.Pop() }, "empty")
+
+	// test the deque
+	d := MakeDeque(2)
+	for i := 0; i < 5; i++ {
+		d.PushFront(-i)
+		d.PushBack(i + 1)
+	}
+	assertSeqEqual(t, d, -4, -3, -2, -1, 0, 1, 2, 3, 4, 5)
+	assertEqual(t, d.PopFront(), -4)
+	assertEqual(t, d.PopBack(), 5)
+	assertEqual(t, d.PeekFront(), -3)
+	assertEqual(t, d.PeekBack(), 4)
+	assertEqual(t, d.Dequeue(), -3)
+	d.Enqueue(7)
+	assertEqual(t, d.Peek(), -2)
+	v, ok = d.TryPeekBack()
+	assertTrue(t, ok && v == 7, "d.TryPeekBack")
+	v, ok = d.TryPopBack()
+	assertTrue(t, ok && v == 7, "d.TryPopBack")
+	v, ok = d.TryPeekFront()
+	assertTrue(t, ok && v == -2, "d.TryPeekFront")
+	v, ok = d.TryPopFront()
+	assertTrue(t, ok && v == -2, "d.TryPopFront")
+	assertSeqEqual(t, d, -1, 0, 1, 2, 3, 4)
+	d.Clear()
+	_, ok = d.TryPopBack()
+	assertFalse(t, ok, "empty d.TryPopBack")
+	assertPanic(t, func() { d.PeekBack() }, "empty")
+
+	// test that modification during iteration is detected
+	d.PushBack(1)
+	d.PushBack(2)
+	i := d.Iterator()
+	i.Next()
+	d.PushBack(3)
+	assertPanic(t, func() { i.Current() }, "modified")
+	assertPanic(t, func() { i.Next() }, "modified")
+	assertPanic(t, func() { d.Iterator().Current() }, "outside sequence")
 }
 
 type S struct {
 	k, v T
 }
 
+// an IP address, similar to net.IP, which is used to test RegisterComparer
+type ipAddr []byte
+
+// a version number that implements Equatable and Comparable, ignoring the label
+type version struct {
+	major, minor int
+	label        string
+}
+
+func (v version) CompareTo(o T) int {
+	ov := o.(version)
+	if v.major != ov.major {
+		return v.major - ov.major
+	}
+	return v.minor - ov.minor
+}
+
+func (v version) Equals(o T) bool {
+	return v.CompareTo(o) == 0
+}
+
+func (v version) Hash() uint64 {
+	return uint64(v.major)<<32 | uint64(v.minor)
+}
+
 type SD struct {
 	S
 }
@@ -303,6 +908,130 @@ func TestRegistration(t *testing.T) {
 	assertSeqEqual(t, s, Pair{7, 11})
 	d, _ := ToDictionary(S{7, 11})
 	assertEqual(t, d.Get(7), 11)
+
+	equal, hash := func(a, b T) bool { return GenericEqual(a, b) }, func(T) uint64 { return 0 }
+	assertPanic(t, func() { RegisterComparer(nil, equal, nil, hash) }, "argument was nil")
+	assertPanic(t, func() { RegisterComparer(reflect.TypeOf(ipAddr{}), nil, nil, nil) }, "argument was nil")
+	assertPanic(t, func() { RegisterComparer(reflect.TypeOf(ipAddr{}), equal, nil, nil) }, "given together")
+
+	// register a comparer for a slice type, which would normally be compared by pointer
+	ip1, ip2, ip3 := ipAddr{10, 0, 0, 1}, ipAddr{10, 0, 0, 1}, ipAddr{9, 0, 0, 1}
+	RegisterComparer(reflect.TypeOf(ipAddr{}),
+		func(a, b T) bool { return string(a.(ipAddr)) == string(b.(ipAddr)) },
+		func(a, b T) bool { return string(a.(ipAddr)) < string(b.(ipAddr)) },
+		func(v T) uint64 { return GenericHash(string(v.(ipAddr))) })
+	assertTrue(t, GenericEqual(ip1, ip2), "equal(ip1, ip2)")
+	assertFalse(t, GenericEqual(ip1, ip3), "equal(ip1, ip3)")
+	assertEqual(t, GenericHash(ip1), GenericHash(ip2))
+	assertTrue(t, GenericLessThan(ip3, ip1), "ip3 < ip1")
+	assertFalse(t, GenericLessThan(ip1, ip2), "ip1 < ip2")
+	assertTrue(t, MakeContainsComparer(ip1)(ip2), "ip1 c= ip2")
+	assertTrue(t, GenericEqual(Pair{ip1, 1}, Pair{ip2, 1}), "equal(pair(ip1,1), pair(ip2,1))")
+	assertEqual(t, GenericHash(Pair{ip1, 1}), GenericHash(Pair{ip2, 1}))
+	assertEqual(t, MakeHashSetFrom(TSequence{ip1, ip2, ip3, Pair{ip1, 1}, Pair{ip2, 1}}).Count(), 3)
+	ips := TSequence{ip1, ip3}
+	sort.Sort(ips)
+	assertSeqEqual(t, ips, ip3, ip1)
+
+	// register a comparer for a type that could otherwise be used as a map key, but only with a less-than function
+	type point struct{ X, Y int }
+	RegisterComparer(reflect.TypeOf(point{}), nil, func(a, b T) bool { return a.(point).Y < b.(point).Y }, nil)
+	assertTrue(t, GenericLessThan(point{1, 1}, point{0, 2}), "(1,1) < (0,2)")
+	assertFalse(t, GenericEqual(point{1, 1}, point{0, 1}), "equal((1,1), (0,1))")
+	// now register an equality comparer for it that ignores X. this should override the go map semantics
+	type wrapper struct{ P point }
+	assertEqual(t, MakeHashSetFrom(TSequence{wrapper{point{1, 1}}, wrapper{point{0, 1}}}).Count(), 2)
+	RegisterComparer(reflect.TypeOf(point{}),
+		func(a, b T) bool { return a.(point).Y == b.(point).Y }, nil, func(v T) uint64 { return uint64(v.(point).Y) })
+	assertTrue(t, GenericEqual(point{1, 1}, point{0, 1}), "equal((1,1), (0,1))")
+	assertEqual(t, MakeHashSetFrom(TSequence{point{1, 1}, point{0, 1}, point{0, 2}}).Count(), 2)
+	assertEqual(t, MakeHashSetFrom(TSequence{wrapper{point{1, 1}}, wrapper{point{0, 1}}}).Count(), 1) // the cached type info was reset
+}
+
+func TestSortedDictionary(t *testing.T) {
+	t.Parallel()
+
+	var d SortedDictionary
+	for _, k := range []int{5, 1, 9, 3, 7} {
+		d.Set(k, strconv.Itoa(k))
+	}
+	assertSeqEqual(t, &d, Pair{1, "1"}, Pair{3, "3"}, Pair{5, "5"}, Pair{7, "7"}, Pair{9, "9"})
+	assertDictionaryEqual(t, &d, 1, "1", 3, "3", 5, "5", 7, "7", 9, "9")
+	assertTrue(t, d.Contains(Pair{3, "3"}), "d.Contains([3,'3'])")
+	assertFalse(t, d.Contains(Pair{3, "4"}), "d.Contains([3,'4'])")
+	assertFalse(t, d.Contains(3), "d.Contains(3)")
+	assertTrue(t, d.ContainsKey(7), "d.ContainsKey(7)")
+	assertFalse(t, d.ContainsKey(8), "d.ContainsKey(8)")
+	assertPanic(t, func() { d.Get(8) }, "not in map")
+	d.Set(3, "three")
+	assertEqual(t, d.Get(3), "three")
+	assertEqual(t, d.Count(), 5)
+
+	// test range queries and floor/ceiling lookups
+	assertSeqEqual(t, d.Range(2, 7), Pair{3, "three"}, Pair{5, "5"}, Pair{7, "7"})
+	assertSeqEqual(t, d.Range(3, 3), Pair{3, "three"})
+	assertSeqEqual(t, d.Range(10, 20))
+	assertSeqEqual(t, d.Range(7, 2))
+	p, ok := d.Floor(6)
+	assertTrue(t, ok && p.Key == 5, "d.Floor(6)")
+	p, ok = d.Floor(7)
+	assertTrue(t, ok && p.Key == 7, "d.Floor(7)")
+	_, ok = d.Floor(0)
+	assertFalse(t, ok, "d.Floor(0)")
+	p, ok = d.Ceiling(6)
+	assertTrue(t, ok && p.Key == 7, "d.Ceiling(6)")
+	p, ok = d.Ceiling(1)
+	assertTrue(t, ok && p.Key == 1, "d.Ceiling(1)")
+	_, ok = d.Ceiling(10)
+	assertFalse(t, ok, "d.Ceiling(10)")
+	p, ok = d.First()
+	assertTrue(t, ok && p.Key == 1, "d.First()")
+	p, ok = d.Last()
+	assertTrue(t, ok && p.Key == 9, "d.Last()")
+
+	// test removal and modification during iteration
+	d.Remove(5)
+	d.Remove(42)
+	assertDictionaryEqual(t, &d, 1, "1", 3, "three", 7, "7", 9, "9")
+	i := d.Iterator()
+	i.Next()
+	d.Set(4, "4")
+	assertPanic(t, func() { i.Current() }, "modified")
+	d.Clear()
+	assertSeqEqual(t, &d)
+	_, ok = d.First()
+	assertFalse(t, ok, "empty d.First()")
+	_, ok = d.Last()
+	assertFalse(t, ok, "empty d.Last()")
+
+	// test a custom comparer that sorts in reverse order
+	rd := MakeSortedDictionary(func(a, b T) bool { return a.(string) > b.(string) })
+	rd.Set("a", 1)
+	rd.Set("c", 3)
+	rd.Set("b", 2)
+	assertSeqEqual(t, rd, Pair{"c", 3}, Pair{"b", 2}, Pair{"a", 1})
+
+	// compare against a map with many random insertions and deletions, checking that the tree stays balanced
+	rnd, m := rand.New(rand.NewSource(1)), make(map[int]int)
+	for n := 0; n < 5000; n++ {
+		k := rnd.Intn(500)
+		if rnd.Intn(3) == 0 {
+			delete(m, k)
+			d.Remove(k)
+		} else {
+			m[k] = n
+			d.Set(k, n)
+		}
+	}
+	assertEqual(t, d.Count(), len(m))
+	prev := -1
+	for i := d.Iterator(); i.Next(); {
+		p := i.Current().(Pair)
+		assertTrue(t, p.Key.(int) > prev, "keys are in order")
+		assertEqual(t, p.Value, m[p.Key.(int)])
+		prev = p.Key.(int)
+	}
+	checkRBTree(t, d.root)
 }
 
 func TestSlicing(t *testing.T) {
@@ -468,6 +1197,19 @@ func assertSeqEqual(t *testing.T, seq Sequence, values ...T) {
 	assertSlicesEqual(t, ToSlice(seq), values...) // test double iteration of the sequence
 }
 
+func assertSetEqual(t *testing.T, s *HashSet, values ...T) {
+	assertEqual(t, s.Count(), len(values))
+	for _, v := range values {
+		if !s.Contains(v) {
+			t.Fatalf("set doesn't contain %v", v)
+		}
+	}
+	n := 0
+	for i := s.Iterator(); i.Next(); n++ {
+	}
+	assertEqual(t, n, len(values))
+}
+
 func assertSlicesEqual(t *testing.T, a []T, b ...T) {
 	index := 0
 	failed := false
@@ -496,6 +1238,29 @@ func rangef(max int) IteratorFunc {
 	}
 }
 
+// Checks that the tree satisfies the left-leaning red-black tree invariants, and returns its black height.
+func checkRBTree(t *testing.T, n *rbNode) int {
+	if n == nil {
+		return 0
+	}
+	assertFalse(t, n.right.isRed(), "right-leaning red link")
+	assertFalse(t, n.red && n.left.isRed(), "two red links in a row")
+	lh, rh := checkRBTree(t, n.left), checkRBTree(t, n.right)
+	assertEqual(t, lh, rh)
+	if !n.red {
+		lh++
+	}
+	return lh
+}
+
+func drain(q Queue) []T {
+	var items []T
+	for q.Count() != 0 {
+		items = append(items, q.Dequeue())
+	}
+	return items
+}
+
 func readField(v T, name string) T {
 	rv := reflect.ValueOf(v)
 	// create an addressable copy of rv
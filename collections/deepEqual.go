@@ -0,0 +1,177 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+import "reflect"
+
+// Determines whether two items are deeply equal. Unlike GenericEqual, which compares slices, maps, and pointers by identity,
+// DeepEqual compares them by their contents, recursing through slices, maps, pointers, arrays, structs (including unexported fields),
+// Pairs, and other interface values. Cyclic data structures are handled. Nil and empty slices and maps are not deeply equal, and
+// functions and channels are still compared by identity. Values whose type was registered with RegisterComparer or that implement
+// Equatable are compared with their custom comparison, as in GenericEqual.
+func DeepEqual(a, b T) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) {
+		return false
+	} else if ta == nil {
+		return true
+	}
+	var visited map[deepVisit]bool
+	return deepEqualValues(reflect.ValueOf(a), reflect.ValueOf(b), &visited)
+}
+
+// Returns a hash code for a value that is consistent with DeepEqual, so that values that are deeply equal have the same hash code.
+func DeepHash(v T) uint64 {
+	if v == nil {
+		return 0
+	}
+	return deepHashValue(reflect.ValueOf(v), maxDeepHashDepth)
+}
+
+// the maximum number of references (pointers, slices, and maps) that DeepHash will follow. this bounds the work done for deep
+// structures, and lets cyclic structures be hashed consistently without tracking visited references
+const maxDeepHashDepth = 8
+
+// identifies a pair of references being compared by deepEqualValues, to detect cycles
+type deepVisit struct {
+	a, b uintptr
+	t    reflect.Type
+}
+
+func deepEqualValues(a, b reflect.Value, visited *map[deepVisit]bool) bool {
+	kind := a.Kind()
+	if kind != reflect.Interface && a.CanInterface() && hasCustomEquality(a.Type()) {
+		return customEqual(a.Type(), a.Interface(), b.Interface())
+	}
+
+	switch kind {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		} else if kind != reflect.Ptr && a.Len() != b.Len() {
+			return false
+		} else if a.Pointer() == b.Pointer() { // if they point to the same data, they're equal
+			return true
+		}
+		// if we're already comparing these references, assume they're equal. if they're not, the earlier comparison will find out
+		v := deepVisit{a.Pointer(), b.Pointer(), a.Type()}
+		if (*visited)[v] {
+			return true
+		} else if *visited == nil {
+			*visited = make(map[deepVisit]bool)
+		}
+		(*visited)[v] = true
+
+		switch kind {
+		case reflect.Ptr:
+			return deepEqualValues(a.Elem(), b.Elem(), visited)
+		case reflect.Slice:
+			for i := 0; i < a.Len(); i++ {
+				if !deepEqualValues(a.Index(i), b.Index(i), visited) {
+					return false
+				}
+			}
+		default: // reflect.Map
+			for i := a.MapRange(); i.Next(); {
+				bv := b.MapIndex(i.Key())
+				if !bv.IsValid() || !deepEqualValues(i.Value(), bv, visited) {
+					return false
+				}
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !deepEqualValues(a.Index(i), b.Index(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < a.NumField(); i++ {
+			if t.Field(i).Name != "_" && !deepEqualValues(a.Field(i), b.Field(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+		return a.Type() == b.Type() && deepEqualValues(a, b, visited)
+	default: // other values are compared the same way as in GenericEqual
+		return equalValues(a, b)
+	}
+}
+
+// Computes a hash code for a value that is consistent with deepEqualValues, following at most the given number of references.
+func deepHashValue(v reflect.Value, depth int) uint64 {
+	kind := v.Kind()
+	if kind != reflect.Interface && v.CanInterface() && hasCustomEquality(v.Type()) {
+		return customHash(v.Type(), v.Interface())
+	}
+
+	switch kind {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if v.IsNil() || depth == 0 {
+			return 0
+		}
+		switch kind {
+		case reflect.Ptr:
+			return deepHashValue(v.Elem(), depth-1)
+		case reflect.Slice:
+			hash := hashUint(uint64(v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				hash = combineHash(hash, deepHashValue(v.Index(i), depth-1))
+			}
+			return hash
+		default: // reflect.Map
+			hash := hashUint(uint64(v.Len()))
+			for i := v.MapRange(); i.Next(); { // add the entry hashes so the result doesn't depend on the iteration order
+				hash += combineHash(deepHashValue(i.Key(), depth-1), deepHashValue(i.Value(), depth-1))
+			}
+			return hash
+		}
+	case reflect.Array:
+		hash := uint64(14695981039346656037)
+		for i := 0; i < v.Len(); i++ {
+			hash = combineHash(hash, deepHashValue(v.Index(i), depth))
+		}
+		return hash
+	case reflect.Struct:
+		hash, t := uint64(14695981039346656037), v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).Name != "_" {
+				hash = combineHash(hash, deepHashValue(v.Field(i), depth))
+			}
+		}
+		return hash
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return deepHashValue(v.Elem(), depth)
+	default: // other values are hashed the same way as in GenericHash
+		return hashValue(v)
+	}
+}
@@ -20,7 +20,11 @@ Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
 
 package collections
 
-import "reflect"
+import (
+	"math"
+	"reflect"
+	"sync"
+)
 
 // Returns a function designed to check whether an item in a sequence matches the given item. The comparison is inherently one-sided
 // and is not identical to using GenericEqual, in that a nil item will match zero pointers of all types, but zero pointers will not
@@ -32,35 +36,87 @@ func MakeContainsComparer(item T) func(T) bool {
 
 // Determines whether two items are equal. This is similar to the behavior of go's == operator, but it can compare many types that ==
 // cannot. It does not share the behavior of MakeContainsComparer of considering nil to match zero pointers because unlike
-// MakeContainsComparer it's not doing a one-sided comparison.
+// MakeContainsComparer it's not doing a one-sided comparison. Slices, maps, and functions are compared by pointer, and arrays and
+// structs whose fields can't be compared with == (such as Pairs) are compared one element or field at a time. Values whose type was
+// registered with RegisterComparer are compared with the registered function, and values that implement Equatable are compared with
+// their Equals method.
 func GenericEqual(a, b T) bool {
 	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
 	if ta != tb { // if they're different types, they aren't equal
 		return false
 	} else if ta == nil { // if both are nil, they're equal
 		return true
+	} else if hasCustomEquality(ta) { // if the type has a custom comparison, use it
+		return customEqual(ta, a, b)
 	} else if !isEquatable(ta.Kind()) { // if the values can't generally be compared with ==...
 		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer() // compare the pointers
-	} else if ta != pairType { // otherwise, if if the kinds can be compared via ==
-		return a == b // do so. this can panic if the items are structs with incomparable field values. oh well.
-	} else { // we special-case Pair so we can compare Pairs with normally incomparable field values
-		pa, pb := a.(Pair), b.(Pair)
-		return GenericEqual(pa.Key, pb.Key) && GenericEqual(pa.Value, pb.Value)
+	} else if isMapKeyType(ta) { // otherwise, if the values can always be compared via ==
+		return a == b // do so
+	} else { // otherwise, they're arrays or structs that may contain incomparable values, so compare them piece by piece
+		return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
 	}
 }
 
+// Returns a hash code for a value that is consistent with GenericEqual, so that values that are equal according to GenericEqual
+// have the same hash code. Slices, maps, and functions are hashed by pointer, and arrays and structs are hashed structurally. Values
+// whose type was registered with RegisterComparer are hashed with the registered function, and values that implement Equatable are
+// hashed with their Hash method.
+func GenericHash(v T) uint64 {
+	if v == nil {
+		return 0
+	} else if len(comparers) == 0 { // if there are no registered comparers, special-case some common types to avoid reflection
+		switch v := v.(type) {
+		case int:
+			return hashUint(uint64(v))
+		case string:
+			return hashString(v)
+		}
+	}
+	return hashValue(reflect.ValueOf(v))
+}
+
+// Registers functions that GenericEqual, GenericHash, and GenericLessThan (and thus MakeContainsComparer, the hash-based
+// collections, and the linq operators that use them by default) will use to compare values of the given type, which is useful for
+// types you don't control. The equal and hash functions must be given together or not at all, and values that are equal must have
+// the same hash code. The less function may be nil if the type has no natural ordering. The functions will only be passed values of
+// the given type. Registered functions take precedence over the Equatable and Comparable interfaces, and registering a type again
+// replaces all of its functions. Registration is not synchronized, so registering a comparer while values are being compared or
+// hashed on other goroutines is a data race. Register comparers before they're needed, for example in an init function.
+func RegisterComparer(t reflect.Type, equal func(a, b T) bool, less func(a, b T) bool, hash func(T) uint64) {
+	if t == nil || equal == nil && less == nil {
+		panic("argument was nil")
+	} else if (equal == nil) != (hash == nil) {
+		panic("equal and hash must be given together")
+	}
+	comparers[t] = registeredComparer{equal, less, hash}
+	mapKeyTypes.Range(func(k, _ interface{}) bool { // types containing t may no longer be usable as map keys
+		mapKeyTypes.Delete(k)
+		return true
+	})
+}
+
 type containsComparer struct {
 	item, value T // item doubles as the key in a pair match
 	itemPtr     uintptr
 	isEquatable bool
 	isPair      bool
+	useGeneric  bool // true if item should be compared with GenericEqual (e.g. because it's Equatable or contains incomparable values)
 }
 
-// the type of a Pair. equality comparisons of structs containing incomparable field values doesn't work. there's not much we can do
-// unless we use reflection to recursively compare them one field at a time. (yuck!) but since Pair structs are so common here, we'll
-// special-case those
+// the type of a Pair. equality comparisons of structs containing incomparable field values don't work with ==, so we have to
+// compare them one field at a time via reflection. but since Pair structs are so common here, we'll special-case those
 var pairType = reflect.TypeOf(Pair{})
 
+var equatableType = reflect.TypeOf((*Equatable)(nil)).Elem()
+
+type registeredComparer struct {
+	equal, less func(a, b T) bool
+	hash        func(T) uint64
+}
+
+// comparers registered with RegisterComparer
+var comparers = make(map[reflect.Type]registeredComparer)
+
 // Creates a containsComparer object initialized with information about an item being compared against, so we can speed up
 // comparisons against it. The comparison is inherently one-sided and is not identical to using GenericEqual, in that a
 // nil item will match zero pointers of all types, but zero pointers will not match nil. This allows doing s.Contains(nil) to
@@ -71,11 +127,15 @@ func makeContainsComparer(item T) containsComparer {
 	t := reflect.TypeOf(item)
 	if t != nil {
 		cmp.isEquatable = isEquatable(t.Kind())
-		if cmp.isEquatable {
+		if hasCustomEquality(t) {
+			cmp.useGeneric = true
+		} else if cmp.isEquatable {
 			cmp.isPair = t == pairType // special-case Pair so we can compare pairs with normally incomparable fields
 			if cmp.isPair {
 				p := item.(Pair)
 				cmp.item, cmp.value = p.Key, p.Value
+			} else {
+				cmp.useGeneric = !isMapKeyType(t) // use GenericEqual for arrays and structs that may contain incomparable values
 			}
 		} else {
 			cmp.itemPtr = reflect.ValueOf(item).Pointer()
@@ -91,12 +151,14 @@ func (cmp *containsComparer) Equal(elem T) bool {
 		// if one item is comparable with == and the other is not, they don't match. there is one exception: when elem is a pointer
 		// and cmp.item is nil. in that case cmp.isComparable is false and isComparable(k) is true. we want to continue on to the
 		// final check where we compare pointers because a nil cmp.item matches zero pointers of all types
+	} else if cmp.useGeneric { // if the item needs special handling, use GenericEqual
+		return GenericEqual(cmp.item, elem)
 	} else if k := t.Kind(); (k != reflect.Ptr || cmp.item != nil) && cmp.isEquatable != isEquatable(k) {
 		return false
 	} else if !cmp.isEquatable { // if the items can't be compared with ==, compare the pointers
 		return reflect.ValueOf(elem).Pointer() == cmp.itemPtr // this handles slices, maps, functions, and comparisons of pointers against nil
-	} else if !cmp.isPair { // if the objects can normally be compared with == and we're not comparing Pair objects...
-		return elem == cmp.item // this can panic if the items are structs with incomparable field values. oh well.
+	} else if !cmp.isPair { // if the objects can always be compared with == and we're not comparing Pair objects...
+		return elem == cmp.item
 	} else if t == pairType { // special-case Pair because they're so common here and we don't want to fail on Pairs with incomparable field values
 		p := elem.(Pair)
 		return GenericEqual(cmp.item, p.Key) && GenericEqual(cmp.value, p.Value)
@@ -104,7 +166,191 @@ func (cmp *containsComparer) Equal(elem T) bool {
 	return false
 }
 
+// Compares two values of a type for which hasCustomEquality is true.
+func customEqual(t reflect.Type, a, b T) bool {
+	if c, ok := comparers[t]; ok && c.equal != nil {
+		return c.equal(a, b)
+	}
+	return a.(Equatable).Equals(b)
+}
+
+// Hashes a value of a type for which hasCustomEquality is true.
+func customHash(t reflect.Type, v T) uint64 {
+	if c, ok := comparers[t]; ok && c.hash != nil {
+		return c.hash(v)
+	}
+	return v.(Equatable).Hash()
+}
+
+// Mixes a value into a hash code, in the style of FNV-1a.
+func combineHash(hash, v uint64) uint64 {
+	return (hash ^ v) * 1099511628211
+}
+
+// Compares two values of the same type in the same way as GenericEqual, but recursively via reflection so it works on values
+// that can't be converted back into interfaces (such as unexported fields).
+func equalValues(a, b reflect.Value) bool {
+	if a.Kind() != reflect.Interface && a.CanInterface() && hasCustomEquality(a.Type()) {
+		return customEqual(a.Type(), a.Interface(), b.Interface())
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < a.NumField(); i++ {
+			if t.Field(i).Name != "_" && !equalValues(a.Field(i), b.Field(i)) { // == ignores blank fields, so we do too
+				return false
+			}
+		}
+		return true
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+		return a.Type() == b.Type() && equalValues(a, b)
+	default: // pointers, channels, functions, maps, and slices are compared by pointer
+		return a.Pointer() == b.Pointer()
+	}
+}
+
+// Computes a hash code for a floating-point number, making sure that positive and negative zero (which are equal) hash the same.
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	return hashUint(math.Float64bits(f))
+}
+
+// Computes a hash code for a string using FNV-1a.
+func hashString(s string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		hash = combineHash(hash, uint64(s[i]))
+	}
+	return hash
+}
+
+// Scrambles the bits of an integer so that similar integers don't have similar hash codes.
+func hashUint(v uint64) uint64 {
+	v = (v ^ (v >> 30)) * 0xbf58476d1ce4e5b9
+	v = (v ^ (v >> 27)) * 0x94d049bb133111eb
+	return v ^ (v >> 31)
+}
+
+// Computes a hash code for a value that is consistent with equalValues.
+func hashValue(v reflect.Value) uint64 {
+	if v.Kind() != reflect.Interface && v.CanInterface() && hasCustomEquality(v.Type()) {
+		return customHash(v.Type(), v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return hashUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return hashUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return combineHash(hashFloat(real(c)), hashFloat(imag(c)))
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Array:
+		hash := uint64(14695981039346656037)
+		for i := 0; i < v.Len(); i++ {
+			hash = combineHash(hash, hashValue(v.Index(i)))
+		}
+		return hash
+	case reflect.Struct:
+		hash, t := uint64(14695981039346656037), v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).Name != "_" {
+				hash = combineHash(hash, hashValue(v.Field(i)))
+			}
+		}
+		return hash
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return hashValue(v.Elem())
+	default: // pointers, channels, functions, maps, and slices are hashed by pointer
+		return hashUint(uint64(v.Pointer()))
+	}
+}
+
+// Determines whether values of the given type have a registered equality comparer or implement Equatable.
+func hasCustomEquality(t reflect.Type) bool {
+	if len(comparers) != 0 {
+		if c, ok := comparers[t]; ok && c.equal != nil {
+			return true
+		}
+	}
+	return t.NumMethod() != 0 && t.Implements(equatableType)
+}
+
 // Determines whether a value can be compared with another value of the same type using the == operator.
 func isEquatable(kind reflect.Kind) bool {
 	return kind <= reflect.Array || (kind != reflect.Func && kind != reflect.Slice && kind != reflect.Map)
 }
+
+// Determines whether a value's type (or nil) can always be used as a go map key, in which case == and GenericEqual agree on it. This
+// is true of all types that can be compared with == except interfaces and arrays and structs containing interfaces, since the values
+// inside an interface may not be comparable, and types containing Equatable values, since == would ignore their custom equality.
+func isMapKey(v T) bool {
+	t := reflect.TypeOf(v)
+	return t == nil || isMapKeyType(t)
+}
+
+// Determines whether values of the given type can always be used as go map keys. See isMapKey.
+func isMapKeyType(t reflect.Type) bool {
+	if hasCustomEquality(t) { // go maps would ignore the custom equality
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Struct:
+		if v, ok := mapKeyTypes.Load(t); ok {
+			return v.(bool)
+		}
+		ok := true
+		if t.Kind() == reflect.Array {
+			ok = isMapKeyType(t.Elem())
+		} else {
+			for i := 0; ok && i < t.NumField(); i++ {
+				ok = isMapKeyType(t.Field(i).Type)
+			}
+		}
+		mapKeyTypes.Store(t, ok)
+		return ok
+	case reflect.Func, reflect.Interface, reflect.Map, reflect.Slice:
+		return false
+	default:
+		return true
+	}
+}
+
+// a cache of whether array and struct types can be used as map keys, since computing it requires walking the type's fields
+var mapKeyTypes sync.Map
@@ -79,6 +79,15 @@ func (i *$IT) Next() bool {
 func (s $ST) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
+
+func (s $ST) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
 EOF
 
 if [[ "$T" != "T" ]]; then
@@ -0,0 +1,228 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+// A HashSet is an unordered Collection of distinct items. Items are compared with GenericEqual and hashed with GenericHash (unless
+// the set was created with custom functions), so the set can hold slices, Pairs, and other values that can't be used as go map keys.
+// The set algebra methods accept any Sequence, and treat it as a set (i.e. duplicates are ignored). The zero value is an empty set
+// ready for use.
+type HashSet struct {
+	table   hashTable
+	version int // incremented whenever the set is modified, so iterators can detect changes
+}
+
+var _ Collection = &HashSet{}
+
+// Creates a new HashSet with enough space to hold the given number of items before it needs to grow.
+func MakeHashSet(capacity int) *HashSet {
+	return &HashSet{table: makeHashTable(capacity)}
+}
+
+// Creates a new HashSet containing the distinct items from the given sequence.
+func MakeHashSetFrom(seq Sequence) *HashSet {
+	capacity := 0
+	if col, ok := seq.(Collection); ok {
+		capacity = col.Count()
+	}
+	s := MakeHashSet(capacity)
+	s.UnionWith(seq)
+	return s
+}
+
+// Creates a new HashSet that compares items with the given equality function and hashes them with the given hash function, which
+// must return the same hash code for equal items. For example, passing DeepEqual and DeepHash creates a set that compares items by
+// their contents. If equal is nil, GenericEqual and GenericHash are used. If hash is nil, all items get the same hash code, so the
+// set works but operations take time proportional to the size of the set.
+func MakeHashSetWithComparer(capacity int, equal func(a, b T) bool, hash func(T) uint64) *HashSet {
+	s := MakeHashSet(capacity)
+	if equal != nil {
+		s.table.equal, s.table.hash = equal, hash
+	}
+	return s
+}
+
+// Adds an item to the set. Returns true if the item was added or false if it already existed in the set.
+func (s *HashSet) Add(item T) bool {
+	_, added := s.table.lookup(item, true)
+	if added {
+		s.version++
+	}
+	return added
+}
+
+// Removes all items from the set.
+func (s *HashSet) Clear() {
+	s.table.clear()
+	s.version++
+}
+
+// Indicates whether the set contains the given item.
+func (s *HashSet) Contains(item T) bool {
+	i, _ := s.table.lookup(item, false)
+	return i >= 0
+}
+
+// Returns the number of items in the set.
+func (s *HashSet) Count() int {
+	return s.table.count()
+}
+
+// Removes all items in the given sequence from the set.
+func (s *HashSet) ExceptWith(seq Sequence) {
+	if seq == Sequence(s) { // every item is removed. (removing items while iterating the set itself would fail)
+		s.Clear()
+		return
+	}
+	i := seq.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		s.Remove(i.Current())
+	}
+}
+
+// Removes all items from the set that don't exist in the given sequence.
+func (s *HashSet) IntersectWith(seq Sequence) {
+	if s.Count() != 0 {
+		other := s.toHashSet(seq)
+		for i := s.Count() - 1; i >= 0; i-- { // go backwards, since removal moves the last item into the removed item's place
+			if !other.Contains(s.table.entries[i].key) {
+				s.table.removeAt(i)
+			}
+		}
+		s.version++
+	}
+}
+
+// Indicates whether all items in the set exist in the given sequence.
+func (s *HashSet) IsSubsetOf(seq Sequence) bool {
+	if s.Count() == 0 {
+		return true
+	}
+	other := s.toHashSet(seq)
+	if s.Count() > other.Count() {
+		return false
+	}
+	for _, e := range s.table.entries {
+		if !other.Contains(e.key) {
+			return false
+		}
+	}
+	return true
+}
+
+// Indicates whether all items in the given sequence exist in the set.
+func (s *HashSet) IsSupersetOf(seq Sequence) bool {
+	i := seq.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		if !s.Contains(i.Current()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns an iterator that returns the items from the set in an arbitrary order.
+func (s *HashSet) Iterator() Iterator {
+	return &hashSetIterator{s, -1, s.version}
+}
+
+// Indicates whether the set and the given sequence have at least one item in common.
+func (s *HashSet) Overlaps(seq Sequence) bool {
+	if s.Count() != 0 {
+		i := seq.Iterator()
+		defer mustFinishIterator(i)
+		for i.Next() {
+			if s.Contains(i.Current()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Removes an item from the set. Returns true if the item was removed or false if it didn't exist in the set.
+func (s *HashSet) Remove(item T) bool {
+	if i, _ := s.table.lookup(item, false); i >= 0 {
+		s.table.removeAt(i)
+		s.version++
+		return true
+	}
+	return false
+}
+
+// Modifies the set so that it contains the items that exist in either the set or the given sequence, but not both.
+func (s *HashSet) SymmetricExceptWith(seq Sequence) {
+	other := s.toHashSet(seq)
+	if other == s { // every item is in both sets, so the result is empty
+		s.Clear()
+		return
+	}
+	for i := other.Iterator(); i.Next(); {
+		if item := i.Current(); !s.Remove(item) {
+			s.Add(item)
+		}
+	}
+}
+
+// Adds all items in the given sequence to the set.
+func (s *HashSet) UnionWith(seq Sequence) {
+	i := seq.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		s.Add(i.Current())
+	}
+}
+
+// Returns the sequence as a HashSet that compares items the same way as this set, creating one if necessary.
+func (s *HashSet) toHashSet(seq Sequence) *HashSet {
+	if hs, ok := seq.(*HashSet); ok && (hs == s || hs.table.equal == nil && s.table.equal == nil) {
+		return hs
+	}
+	hs := MakeHashSetWithComparer(0, s.table.equal, s.table.hash)
+	hs.UnionWith(seq)
+	return hs
+}
+
+type hashSetIterator struct {
+	s              *HashSet
+	index, version int
+}
+
+func (i *hashSetIterator) Current() T {
+	if i.version != i.s.version {
+		panic("collection was modified during iteration")
+	} else if i.index < 0 || i.index >= i.s.Count() {
+		panic("Current called outside sequence")
+	}
+	return i.s.table.entries[i.index].key
+}
+
+func (i *hashSetIterator) Next() bool {
+	if i.version != i.s.version {
+		panic("collection was modified during iteration")
+	} else if i.index+1 < i.s.Count() {
+		i.index++
+		return true
+	}
+	i.index = i.s.Count()
+	return false
+}
@@ -0,0 +1,136 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+// A hashTable maps keys to values, comparing keys with GenericEqual unless a custom equality function is given. Without a custom
+// equality function, keys that can always be used as go map keys are indexed with a go map, and other keys (such as slices and
+// Pairs) are indexed by their GenericHash codes. With one, all keys are indexed by their custom hash codes. Entries are stored
+// densely so they can be iterated quickly, but removing an entry moves the last entry into its place.
+type hashTable struct {
+	entries []hashEntry
+	index   map[T]int        // maps keys that can be used as go map keys to their indices within entries
+	buckets map[uint64][]int // maps the hash codes of other keys to the indices of the entries having them
+	equal   func(a, b T) bool
+	hash    func(T) uint64 // if equal is set and hash is nil, all keys have a hash code of zero
+}
+
+type hashEntry struct {
+	key, value T
+	hash       uint64 // the hash code of the key, if hashed is true
+	hashed     bool   // true if the key is indexed in buckets rather than index
+}
+
+// Creates a new hashTable with enough space to hold the given number of items before it needs to grow.
+func makeHashTable(capacity int) hashTable {
+	return hashTable{entries: make([]hashEntry, 0, capacity), index: make(map[T]int, capacity)}
+}
+
+// Removes all entries from the table.
+func (h *hashTable) clear() {
+	for i := range h.entries { // clear the slots so we don't keep the items alive
+		h.entries[i] = hashEntry{}
+	}
+	h.entries, h.index, h.buckets = h.entries[:0], nil, nil
+}
+
+// Returns the number of entries in the table.
+func (h *hashTable) count() int {
+	return len(h.entries)
+}
+
+// Returns the index of the entry with the given key. If the key doesn't exist and add is true, an entry with a nil value is added
+// for it. Returns the index of the entry (or -1 if it doesn't exist and wasn't added) and whether the entry was added.
+func (h *hashTable) lookup(key T, add bool) (int, bool) {
+	if h.equal == nil && isMapKey(key) {
+		if i, ok := h.index[key]; ok {
+			return i, false
+		} else if !add {
+			return -1, false
+		}
+		if h.index == nil {
+			h.index = make(map[T]int)
+		}
+		h.index[key] = len(h.entries)
+		h.entries = append(h.entries, hashEntry{key: key})
+	} else {
+		var hash uint64
+		equal := h.equal
+		if equal == nil {
+			equal, hash = GenericEqual, GenericHash(key)
+		} else if h.hash != nil {
+			hash = h.hash(key)
+		}
+		bucket := h.buckets[hash]
+		for _, i := range bucket {
+			if equal(key, h.entries[i].key) {
+				return i, false
+			}
+		}
+		if !add {
+			return -1, false
+		}
+		if h.buckets == nil {
+			h.buckets = make(map[uint64][]int)
+		}
+		h.buckets[hash] = append(bucket, len(h.entries))
+		h.entries = append(h.entries, hashEntry{key: key, hash: hash, hashed: true})
+	}
+	return len(h.entries) - 1, true
+}
+
+// Removes the entry at the given index, moving the last entry into its place.
+func (h *hashTable) removeAt(index int) {
+	h.reindex(&h.entries[index], index, -1)
+	last := len(h.entries) - 1
+	if index != last {
+		h.entries[index] = h.entries[last]
+		h.reindex(&h.entries[index], last, index)
+	}
+	h.entries[last] = hashEntry{} // clear the slot so we don't keep the item alive
+	h.entries = h.entries[:last]
+}
+
+// Updates the index of an entry from oldIndex to newIndex, or removes the entry from the index if newIndex is negative.
+func (h *hashTable) reindex(e *hashEntry, oldIndex, newIndex int) {
+	if !e.hashed {
+		if newIndex < 0 {
+			delete(h.index, e.key)
+		} else {
+			h.index[e.key] = newIndex
+		}
+		return
+	}
+
+	bucket := h.buckets[e.hash]
+	for i, v := range bucket {
+		if v == oldIndex {
+			if newIndex >= 0 {
+				bucket[i] = newIndex
+			} else if len(bucket) == 1 {
+				delete(h.buckets, e.hash)
+			} else {
+				bucket[i] = bucket[len(bucket)-1]
+				h.buckets[e.hash] = bucket[:len(bucket)-1]
+			}
+			break
+		}
+	}
+}
@@ -20,6 +20,8 @@ Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
 
 package collections
 
+import "io"
+
 // T represents a value of any type. It is equivalent to interface{}.
 type T interface{}
 
@@ -33,6 +35,24 @@ type Iterator interface {
 	Next() bool
 }
 
+// A ClosableIterator is an Iterator that holds resources, such as goroutines or open files, that should be released when the iterator
+// is no longer needed. Code that stops iterating before the end of a sequence should call CloseIterator on the iterator, and the
+// iterators returned by the LINQ library close their source iterators when they are closed or abandon them.
+type ClosableIterator interface {
+	Iterator
+	// Releases the resources held by the iterator. After Close is called, Next returns false. Close may be called more than once.
+	io.Closer
+}
+
+// A FallibleIterator is an Iterator over a source that can fail, such as a file or a network connection. When Next returns false,
+// Err reports whether the iteration ended because of an error. The iterators returned by the LINQ library pass along the errors from
+// their source iterators.
+type FallibleIterator interface {
+	Iterator
+	// Returns the error that ended the iteration, or nil if the iteration hasn't ended or ended normally.
+	Err() error
+}
+
 // A Sequence represents a sequence of items, possibly infinite, that can be iterated. It is assumed that iterating the same
 // sequence multiple times will produce the same items each time.
 type Sequence interface {
@@ -61,6 +81,23 @@ type ReadOnlyDictionary interface {
 	TryGet(key T) (T, bool)
 }
 
+// A Comparable is a value that knows how to order itself relative to other values of the same type. GenericLessThan uses it in
+// preference to its built-in rules when both values have the same type.
+type Comparable interface {
+	// Returns a negative number if the value is less than the given value, zero if they're equal, or a positive number if the value
+	// is greater than the given value. The given value will have the same type as the receiver.
+	CompareTo(T) int
+}
+
+// An Equatable is a value that knows how to compare itself for equality with other values of the same type. GenericEqual and
+// GenericHash use it in preference to their built-in rules, so it also affects hash-based collections like HashSet.
+type Equatable interface {
+	// Indicates whether the value equals the given value, which will have the same type as the receiver.
+	Equals(T) bool
+	// Returns a hash code for the value. Values that are equal must have the same hash code.
+	Hash() uint64
+}
+
 // A Dictionary represents a map from keys to values that can be altered. It is also a Sequence of Pair objects.
 type Dictionary interface {
 	ReadOnlyDictionary
@@ -86,6 +123,24 @@ type List interface {
 	Set(index int, item T)
 }
 
+// A MutableList represents a List whose items can be added and removed.
+type MutableList interface {
+	List
+	// Adds an item to the end of the list.
+	Add(item T)
+	// Adds the items from a sequence to the end of the list.
+	AddRange(items Sequence)
+	// Removes all items from the list.
+	Clear()
+	// Inserts an item at the given index, shifting the item at that index and all subsequent items up by one. The index may equal
+	// the length of the list, in which case the item is added to the end. Panics if the index is out of range.
+	Insert(index int, item T)
+	// Removes the item at the given index, shifting all subsequent items down by one. Panics if the index is out of range.
+	RemoveAt(index int)
+	// Removes the given number of items starting at the given index. Panics if the range extends outside the list.
+	RemoveRange(index, count int)
+}
+
 // A Pair represents a key and value. Dictionaries, as well as Sequences based on maps, are sequences of Pairs.
 type Pair struct {
 	Key, Value T
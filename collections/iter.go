@@ -0,0 +1,136 @@
+//go:build go1.23
+// +build go1.23
+
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+import (
+	"iter"
+	"reflect"
+)
+
+// Returns an iter.Seq that yields the items from the sequence, so it can be consumed with a range loop. The sequence's iterator is
+// closed when the loop ends, even if it exits early. If the iteration fails, the loop panics with the error.
+func Items(s Sequence) iter.Seq[T] {
+	if rs, ok := s.(rangeSequence); ok { // if the sequence came from an iter.Seq, use it directly
+		return rs.seq
+	}
+	return func(yield func(T) bool) {
+		i := s.Iterator()
+		defer mustFinishIterator(i)
+		for i.Next() && yield(i.Current()) {
+		}
+	}
+}
+
+// Returns an iter.Seq2 that yields the keys and values of the Pairs in the sequence, so it can be consumed with a range loop. The
+// sequence's iterator is closed when the loop ends, even if it exits early. Panics if an item is not a Pair or the iteration fails.
+func Pairs(s Sequence) iter.Seq2[T, T] {
+	if rs, ok := s.(rangeSequence); ok && rs.pairs != nil { // if the sequence came from an iter.Seq2, use it directly
+		return rs.pairs
+	}
+	return func(yield func(T, T) bool) {
+		i := s.Iterator()
+		defer mustFinishIterator(i)
+		for i.Next() {
+			if p := i.Current().(Pair); !yield(p.Key, p.Value) {
+				break
+			}
+		}
+	}
+}
+
+// Returns a Sequence that iterates an iter.Seq or iter.Seq2 (or any function with the same signature), if the object is one. The
+// items of an iter.Seq2 are returned as Pairs.
+func rangeFuncSequence(obj T, t reflect.Type) (Sequence, bool) {
+	switch f := obj.(type) {
+	case iter.Seq[T]:
+		return rangeSequence{seq: f}, true
+	case func(func(T) bool):
+		return rangeSequence{seq: f}, true
+	case iter.Seq2[T, T]:
+		return makePairsSequence(f), true
+	case func(func(T, T) bool):
+		return makePairsSequence(f), true
+	}
+
+	if t.NumIn() != 1 || t.NumOut() != 0 {
+		return nil, false
+	}
+	yt := t.In(0) // the type of the yield function
+	if yt.Kind() != reflect.Func || yt.IsVariadic() || yt.NumIn() < 1 || yt.NumIn() > 2 || yt.NumOut() != 1 ||
+		yt.Out(0).Kind() != reflect.Bool {
+		return nil, false
+	}
+
+	f := reflect.ValueOf(obj)
+	if yt.NumIn() == 1 {
+		return rangeSequence{seq: func(yield func(T) bool) {
+			f.Call([]reflect.Value{reflect.MakeFunc(yt, func(args []reflect.Value) []reflect.Value {
+				return []reflect.Value{reflect.ValueOf(yield(args[0].Interface())).Convert(yt.Out(0))}
+			})})
+		}}, true
+	}
+	return makePairsSequence(func(yield func(T, T) bool) {
+		f.Call([]reflect.Value{reflect.MakeFunc(yt, func(args []reflect.Value) []reflect.Value {
+			return []reflect.Value{reflect.ValueOf(yield(args[0].Interface(), args[1].Interface())).Convert(yt.Out(0))}
+		})})
+	}), true
+}
+
+func makePairsSequence(pairs iter.Seq2[T, T]) rangeSequence {
+	return rangeSequence{
+		pairs: pairs,
+		seq: func(yield func(T) bool) {
+			for k, v := range pairs {
+				if !yield(Pair{k, v}) {
+					return
+				}
+			}
+		},
+	}
+}
+
+// A rangeSequence is a Sequence based on an iter.Seq. Each iterator pulls items from the iter.Seq on demand, starting it on the
+// first call to Next. Closing the iterator stops the iter.Seq, so its deferred cleanup runs.
+type rangeSequence struct {
+	seq   iter.Seq[T]
+	pairs iter.Seq2[T, T] // the original iter.Seq2, if the sequence was created from one
+}
+
+func (s rangeSequence) Iterator() Iterator {
+	var next func() (T, bool)
+	var stop func()
+	pull := func() (T, bool) {
+		if next == nil {
+			next, stop = iter.Pull(s.seq)
+		}
+		return next()
+	}
+	release := func() error {
+		if stop != nil {
+			stop()
+		}
+		return nil
+	}
+	return &closableFunctionIterator{f: infallible(pull), close: release}
+}
@@ -23,12 +23,36 @@ package collections
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 var timeType = reflect.TypeOf(time.Time{})
 
-// Determines whether a < b in a generic fashion that allows almost any value to be compared with almost any other value.
+// the built-in types for each boolean, numeric, and string kind, indexed by kind
+var builtinTypes = [...]reflect.Type{
+	reflect.Bool: reflect.TypeOf(false), reflect.Int: reflect.TypeOf(int(0)), reflect.Int8: reflect.TypeOf(int8(0)),
+	reflect.Int16: reflect.TypeOf(int16(0)), reflect.Int32: reflect.TypeOf(int32(0)), reflect.Int64: reflect.TypeOf(int64(0)),
+	reflect.Uint: reflect.TypeOf(uint(0)), reflect.Uint8: reflect.TypeOf(uint8(0)), reflect.Uint16: reflect.TypeOf(uint16(0)),
+	reflect.Uint32: reflect.TypeOf(uint32(0)), reflect.Uint64: reflect.TypeOf(uint64(0)), reflect.Uintptr: reflect.TypeOf(uintptr(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)), reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.Complex64: reflect.TypeOf(complex64(0)), reflect.Complex128: reflect.TypeOf(complex128(0)),
+	reflect.String: reflect.TypeOf(""),
+}
+
+// Determines whether a < b in a generic fashion that allows almost any value to be compared with almost any other value. If a and b
+// have the same type and a less-than function was registered for it with RegisterComparer, they are compared with that function.
+// Otherwise, if the type implements Comparable, they are compared with its CompareTo method. Values of named boolean, numeric, and
+// string types (e.g. type Age int) are compared like values of their underlying types.
+//
+// Arrays of the same type are compared lexicographically by element. Structs of the same type are compared lexicographically by
+// their exported fields, in declaration order. The order can be customized with a struct tag: `linq:"order=N"` makes a field
+// compare before those with a greater N and before fields without an order, `linq:"desc"` reverses the comparison of a field, and
+// `linq:"-"` excludes a field. Options can be combined, like `linq:"order=2,desc"`. A struct type with no exported fields is not
+// comparable.
 func GenericLessThan(a, b T) bool {
 	var ta reflect.Type
 	var ka reflect.Kind
@@ -37,7 +61,17 @@ func GenericLessThan(a, b T) bool {
 			return false
 		}
 		ta = reflect.TypeOf(a)
+		if len(comparers) != 0 { // if the type has a registered comparer, use it
+			if c, ok := comparers[ta]; ok && c.less != nil && ta == reflect.TypeOf(b) {
+				return c.less(a, b)
+			}
+		}
+		if ca, ok := a.(Comparable); ok && ta == reflect.TypeOf(b) { // if the type knows how to compare itself, let it
+			return ca.CompareTo(b) < 0
+		}
 		ka = ta.Kind()
+		a, _ = toBuiltinType(a) // the code below uses type assertions that would fail with named types
+		b, _ = toBuiltinType(b)
 	}
 	switch ka {
 	case reflect.Invalid: // a is nil
@@ -94,10 +128,28 @@ func GenericLessThan(a, b T) bool {
 			return ka < kb
 		} else if ta == tb && ta == timeType {
 			return a.(time.Time).Before(b.(time.Time))
-		} else {
-			panic(fmt.Sprintf("type %T is not comparable", a))
+		} else if ta == tb && ka == reflect.Array {
+			return arrayLessThan(reflect.ValueOf(a), reflect.ValueOf(b))
+		} else if ta == tb && ka == reflect.Struct {
+			if fields := getOrderFields(ta); len(fields) != 0 {
+				return structLessThan(reflect.ValueOf(a), reflect.ValueOf(b), fields)
+			}
+		}
+		panic(fmt.Sprintf("type %T is not comparable", a))
+	}
+}
+
+// Compares two arrays of the same type lexicographically using GenericLessThan to compare elements.
+func arrayLessThan(a, b reflect.Value) bool {
+	for i := 0; i < a.Len(); i++ {
+		ae, be := a.Index(i).Interface(), b.Index(i).Interface()
+		if GenericLessThan(ae, be) {
+			return true
+		} else if GenericLessThan(be, ae) {
+			return false
 		}
 	}
+	return false
 }
 
 func boolLessThan(a bool, b T) bool {
@@ -195,6 +247,50 @@ func floatLessThan(a float64, b T) bool {
 	}
 }
 
+// Returns the fields of a struct type used to order its values. See GenericLessThan for details.
+func getOrderFields(t reflect.Type) []orderField {
+	if fields, ok := orderFields.Load(t); ok {
+		return fields.([]orderField)
+	}
+
+	var fields, untagged []orderField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" { // skip unexported fields
+			continue
+		}
+		of, hasOrder := orderField{index: i}, false
+		if tag, ok := f.Tag.Lookup("linq"); ok {
+			if tag == "-" {
+				continue
+			}
+			for _, opt := range strings.Split(tag, ",") {
+				if opt == "desc" {
+					of.desc = true
+				} else if strings.HasPrefix(opt, "order=") {
+					n, err := strconv.Atoi(opt[6:])
+					if err != nil {
+						panic(fmt.Sprintf("invalid order in linq tag of field %v.%v", t, f.Name))
+					}
+					of.order, hasOrder = n, true
+				} else if opt != "" {
+					panic(fmt.Sprintf("invalid option %q in linq tag of field %v.%v", opt, t, f.Name))
+				}
+			}
+		}
+		if hasOrder {
+			fields = append(fields, of)
+		} else {
+			untagged = append(untagged, of)
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].order < fields[j].order })
+	fields = append(fields, untagged...)
+
+	orderFields.Store(t, fields)
+	return fields
+}
+
 func intLessThan(a int64, b T) bool {
 	bk := reflect.TypeOf(b).Kind()
 	switch bk {
@@ -237,6 +333,30 @@ func intLessThan(a int64, b T) bool {
 	}
 }
 
+// Compares two structs of the same type lexicographically by the given fields, using GenericLessThan to compare field values.
+func structLessThan(a, b reflect.Value, fields []orderField) bool {
+	for _, f := range fields {
+		af, bf := a.Field(f.index).Interface(), b.Field(f.index).Interface()
+		if GenericLessThan(af, bf) {
+			return !f.desc
+		} else if GenericLessThan(bf, af) {
+			return f.desc
+		}
+	}
+	return false
+}
+
+// Converts a value of a named boolean, numeric, or string type (e.g. type Age int) to its underlying built-in type, and returns
+// true. Other values are returned as-is, with false.
+func toBuiltinType(v T) (T, bool) {
+	if t := reflect.TypeOf(v); t != nil {
+		if k := t.Kind(); int(k) < len(builtinTypes) && builtinTypes[k] != nil && t != builtinTypes[k] {
+			return reflect.ValueOf(v).Convert(builtinTypes[k]).Interface(), true
+		}
+	}
+	return v, false
+}
+
 func uintLessThan(a uint64, b T) bool {
 	bk := reflect.TypeOf(b).Kind()
 	switch bk {
@@ -283,3 +403,11 @@ func uintLessThan(a uint64, b T) bool {
 		return reflect.Uint < bk // we don't need the real type of 'a' since all numerics are adjacent in the enum
 	}
 }
+
+type orderField struct {
+	index, order int
+	desc         bool
+}
+
+// a cache of the fields used to order struct types, since computing them requires walking the type's fields and parsing tags
+var orderFields sync.Map
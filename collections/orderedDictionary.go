@@ -0,0 +1,165 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+import "fmt"
+
+// An OrderedDictionary is a Dictionary that remembers the order in which keys were first added. Iterating the dictionary returns
+// Pairs in that order. Setting the value of an existing key does not change its position, but removing a key and adding it again
+// moves it to the end. Keys are compared with GenericEqual and hashed with GenericHash, so they can be slices, Pairs, and other
+// values that can't be used as go map keys. The zero value is an empty dictionary ready for use.
+type OrderedDictionary struct {
+	index   hashTable   // maps keys to their *orderedNode values
+	head    orderedNode // the sentinel node of a circular, doubly-linked list of items in insertion order
+	version int         // incremented whenever the dictionary is modified, so iterators can detect changes
+}
+
+var _ Dictionary = &OrderedDictionary{}
+
+// Creates a new OrderedDictionary with enough space to hold the given number of items before it needs to grow.
+func MakeOrderedDictionary(capacity int) *OrderedDictionary {
+	d := &OrderedDictionary{index: makeHashTable(capacity)}
+	d.head.prev, d.head.next = &d.head, &d.head
+	return d
+}
+
+// Removes all items from the dictionary.
+func (d *OrderedDictionary) Clear() {
+	d.index.clear()
+	d.head.prev, d.head.next = &d.head, &d.head
+	d.version++
+}
+
+// Indicates whether the dictionary contains the given Pair, comparing values with GenericEqual.
+func (d *OrderedDictionary) Contains(item T) bool {
+	if p, ok := item.(Pair); ok { // a Dictionary is a sequence of Pairs so Contains(T) expects a Pair
+		if v, ok := d.TryGet(p.Key); ok { // get the value by key
+			return GenericEqual(p.Value, v) // and make sure it matches the value from the Pair
+		}
+	}
+	return false
+}
+
+// Indicates whether the dictionary contains the given key.
+func (d *OrderedDictionary) ContainsKey(key T) bool {
+	i, _ := d.index.lookup(key, false)
+	return i >= 0
+}
+
+// Returns the number of items in the dictionary.
+func (d *OrderedDictionary) Count() int {
+	return d.index.count()
+}
+
+// Gets a value from the dictionary given its key, and panics if the key does not exist.
+func (d *OrderedDictionary) Get(key T) T {
+	if v, ok := d.TryGet(key); ok {
+		return v
+	}
+	panic(fmt.Sprintf("key '%v' not in map", key))
+}
+
+// Returns an iterator that returns the items from the dictionary as Pairs, in the order in which their keys were added.
+func (d *OrderedDictionary) Iterator() Iterator {
+	return &orderedDictionaryIterator{d: d, version: d.version}
+}
+
+// Returns a sequence of the keys in the dictionary, in the order in which they were added.
+func (d *OrderedDictionary) Keys() Sequence {
+	return MakeFunctionSequence(func() IteratorFunc {
+		i := d.Iterator().(*orderedDictionaryIterator)
+		return func() (T, bool) {
+			if i.Next() {
+				return i.node.key, true
+			}
+			return nil, false
+		}
+	})
+}
+
+// Removes an item from the dictionary given its key. If the key does not exist, the dictionary is unchanged.
+func (d *OrderedDictionary) Remove(key T) {
+	if i, _ := d.index.lookup(key, false); i >= 0 {
+		n := d.index.entries[i].value.(*orderedNode)
+		d.index.removeAt(i)
+		n.prev.next, n.next.prev = n.next, n.prev
+		n.prev, n.next = nil, nil
+		d.version++
+	}
+}
+
+// Sets a value in the dictionary given its key, overwriting any existing value. If the key is new, it is added to the end.
+func (d *OrderedDictionary) Set(key, value T) {
+	if i, added := d.index.lookup(key, true); !added {
+		d.index.entries[i].value.(*orderedNode).value = value
+	} else {
+		if d.head.next == nil { // if the list is uninitialized (i.e. d is a zero value), initialize it
+			d.head.prev, d.head.next = &d.head, &d.head
+		}
+		n := &orderedNode{key: key, value: value, prev: d.head.prev, next: &d.head}
+		d.head.prev.next, d.head.prev = n, n
+		d.index.entries[i].value = n
+	}
+	d.version++
+}
+
+// Attempts to get a value from the dictionary given its key.
+func (d *OrderedDictionary) TryGet(key T) (T, bool) {
+	if i, _ := d.index.lookup(key, false); i >= 0 {
+		return d.index.entries[i].value.(*orderedNode).value, true
+	}
+	return nil, false
+}
+
+type orderedNode struct {
+	key, value T
+	prev, next *orderedNode
+}
+
+type orderedDictionaryIterator struct {
+	d       *OrderedDictionary
+	node    *orderedNode // the current node, or nil if the iterator hasn't started yet
+	version int
+}
+
+func (i *orderedDictionaryIterator) Current() T {
+	if i.version != i.d.version {
+		panic("collection was modified during iteration")
+	} else if i.node == nil || i.node == &i.d.head {
+		panic("Current called outside sequence")
+	}
+	return Pair{i.node.key, i.node.value}
+}
+
+func (i *orderedDictionaryIterator) Next() bool {
+	if i.version != i.d.version {
+		panic("collection was modified during iteration")
+	}
+	if i.node == nil {
+		i.node = &i.d.head
+	}
+	if i.node.next != nil && i.node.next != &i.d.head {
+		i.node = i.node.next
+		return true
+	}
+	i.node = &i.d.head
+	return false
+}
@@ -0,0 +1,276 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+// A PriorityQueue is a Queue backed by a binary heap, where Dequeue always returns the item with the least value (or the greatest
+// value, if the queue was created in max-first mode). Items are compared with a less-than function, which is GenericLessThan if
+// not specified. The zero value is an empty, min-first queue using GenericLessThan that is ready for use. Iterating the queue returns
+// the items in an arbitrary order.
+type PriorityQueue struct {
+	items      []*PriorityQueueItem
+	isLessThan func(T, T) bool
+	maxFirst   bool
+	version    int // incremented whenever the queue is modified, so iterators can detect changes
+}
+
+// A PriorityQueueItem is a handle to an item in a PriorityQueue, which allows the item to be updated or removed later.
+type PriorityQueueItem struct {
+	value T
+	index int // the index within the heap, or -1 if the item has been removed from the queue
+}
+
+// Returns the item that the handle refers to.
+func (h *PriorityQueueItem) Value() T {
+	return h.value
+}
+
+var _ Queue = &PriorityQueue{}
+
+// Creates a new PriorityQueue that orders items with the given less-than function (or GenericLessThan if nil). If maxFirst is
+// true, the greatest item will be dequeued first. Otherwise, the least item will be dequeued first.
+func MakePriorityQueue(isLessThan func(T, T) bool, maxFirst bool) *PriorityQueue {
+	return &PriorityQueue{isLessThan: isLessThan, maxFirst: maxFirst}
+}
+
+// Creates a new PriorityQueue initialized with the items from the given sequence, which orders items with the given less-than
+// function (or GenericLessThan if nil). If maxFirst is true, the greatest item will be dequeued first. Otherwise, the least item
+// will be dequeued first. The heap is built in linear time.
+func MakePriorityQueueFrom(seq Sequence, isLessThan func(T, T) bool, maxFirst bool) *PriorityQueue {
+	q := MakePriorityQueue(isLessThan, maxFirst)
+	q.EnqueueRange(seq)
+	return q
+}
+
+// Adds an item to the queue and returns a handle that can be used to update or remove it later.
+func (q *PriorityQueue) Add(item T) *PriorityQueueItem {
+	h := &PriorityQueueItem{item, len(q.items)}
+	q.items = append(q.items, h)
+	q.up(h.index)
+	q.version++
+	return h
+}
+
+// Removes all items from the queue. Handles to the items are invalidated.
+func (q *PriorityQueue) Clear() {
+	for i, h := range q.items {
+		h.index = -1
+		q.items[i] = nil
+	}
+	q.items = q.items[:0]
+	q.version++
+}
+
+// Indicates whether the queue contains the given item. Items are compared in the same way as MakeContainsComparer.
+func (q *PriorityQueue) Contains(item T) bool {
+	cmp := makeContainsComparer(item)
+	for _, h := range q.items {
+		if cmp.Equal(h.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the number of items in the queue.
+func (q *PriorityQueue) Count() int {
+	return len(q.items)
+}
+
+// Removes and returns the first item from the queue, or panics if the queue is empty.
+func (q *PriorityQueue) Dequeue() T {
+	if item, ok := q.TryDequeue(); ok {
+		return item
+	}
+	panic("the collection is empty")
+}
+
+// Adds an item to the queue.
+func (q *PriorityQueue) Enqueue(item T) {
+	q.Add(item)
+}
+
+// Adds all items from the given sequence to the queue. If the number of new items is large compared to the size of the queue, the
+// heap will be rebuilt in linear time rather than inserting the items one by one.
+func (q *PriorityQueue) EnqueueRange(seq Sequence) {
+	start := len(q.items)
+	for _, item := range ToSlice(seq) { // read the items first, in case the sequence is the queue itself
+		q.items = append(q.items, &PriorityQueueItem{item, len(q.items)})
+	}
+	if added := len(q.items) - start; added != 0 {
+		if added > start { // if we more than doubled the size of the heap, it's faster to rebuild it from scratch
+			for i := len(q.items)/2 - 1; i >= 0; i-- {
+				q.down(i)
+			}
+		} else {
+			for i := start; i < len(q.items); i++ {
+				q.up(i)
+			}
+		}
+		q.version++
+	}
+}
+
+// Returns an iterator that returns the items from the queue in an arbitrary order.
+func (q *PriorityQueue) Iterator() Iterator {
+	return &priorityQueueIterator{q, -1, q.version}
+}
+
+// Returns the first item from the queue without removing it, or panics if the queue is empty.
+func (q *PriorityQueue) Peek() T {
+	if item, ok := q.TryPeek(); ok {
+		return item
+	}
+	panic("the collection is empty")
+}
+
+// Removes the item referenced by the given handle from the queue. Returns true if the item was removed or false if it had already
+// been removed.
+func (q *PriorityQueue) Remove(h *PriorityQueueItem) bool {
+	if !q.owns(h) {
+		return false
+	}
+	q.removeAt(h.index)
+	return true
+}
+
+// Removes and returns the first item from the queue, if the queue is not empty.
+func (q *PriorityQueue) TryDequeue() (T, bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.removeAt(0), true
+}
+
+// Returns the first item from the queue without removing it, if the queue is not empty.
+func (q *PriorityQueue) TryPeek() (T, bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.items[0].value, true
+}
+
+// Replaces the item referenced by the given handle with a new value, and moves it to the correct position in the queue. Also call
+// this method if the item's priority changed in place (e.g. if the item is a pointer to a mutable object). Panics if the item has
+// been removed from the queue.
+func (q *PriorityQueue) Update(h *PriorityQueueItem, value T) {
+	if !q.owns(h) {
+		panic("the item is not in the queue")
+	}
+	h.value = value
+	if !q.up(h.index) {
+		q.down(h.index)
+	}
+	q.version++
+}
+
+// Moves the item at the given index down the heap until its children are not less than it. Returns true if the item moved.
+func (q *PriorityQueue) down(i int) bool {
+	start, n := i, len(q.items)
+	for {
+		child := 2*i + 1
+		if child >= n {
+			break
+		} else if right := child + 1; right < n && q.less(right, child) {
+			child = right
+		}
+		if !q.less(child, i) {
+			break
+		}
+		q.swap(i, child)
+		i = child
+	}
+	return i != start
+}
+
+// Returns true if the item at index a should be dequeued before the item at index b.
+func (q *PriorityQueue) less(a, b int) bool {
+	isLessThan := q.isLessThan
+	if isLessThan == nil {
+		isLessThan = GenericLessThan
+	}
+	if q.maxFirst {
+		return isLessThan(q.items[b].value, q.items[a].value)
+	}
+	return isLessThan(q.items[a].value, q.items[b].value)
+}
+
+func (q *PriorityQueue) owns(h *PriorityQueueItem) bool {
+	return h != nil && h.index >= 0 && h.index < len(q.items) && q.items[h.index] == h
+}
+
+func (q *PriorityQueue) removeAt(i int) T {
+	h, last := q.items[i], len(q.items)-1
+	if i != last {
+		q.swap(i, last)
+	}
+	q.items[last] = nil
+	q.items = q.items[:last]
+	if i != last && !q.down(i) {
+		q.up(i)
+	}
+	h.index = -1
+	q.version++
+	return h.value
+}
+
+func (q *PriorityQueue) swap(a, b int) {
+	q.items[a], q.items[b] = q.items[b], q.items[a]
+	q.items[a].index, q.items[b].index = a, b
+}
+
+// Moves the item at the given index up the heap until its parent is not greater than it. Returns true if the item moved.
+func (q *PriorityQueue) up(i int) bool {
+	start := i
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.less(i, parent) {
+			break
+		}
+		q.swap(i, parent)
+		i = parent
+	}
+	return i != start
+}
+
+type priorityQueueIterator struct {
+	q              *PriorityQueue
+	index, version int
+}
+
+func (i *priorityQueueIterator) Current() T {
+	if i.version != i.q.version {
+		panic("collection was modified during iteration")
+	} else if i.index < 0 || i.index >= len(i.q.items) {
+		panic("Current called outside sequence")
+	}
+	return i.q.items[i.index].value
+}
+
+func (i *priorityQueueIterator) Next() bool {
+	if i.version != i.q.version {
+		panic("collection was modified during iteration")
+	} else if i.index+1 < len(i.q.items) {
+		i.index++
+		return true
+	}
+	i.index = len(i.q.items)
+	return false
+}
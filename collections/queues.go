@@ -0,0 +1,369 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+// An ArrayQueue is a first-in, first-out Queue backed by a circular buffer that grows as needed. The zero value is an empty queue
+// ready for use. Iterating the queue returns the items in the order they would be dequeued.
+type ArrayQueue struct {
+	ring
+}
+
+var _ Queue = &ArrayQueue{}
+
+// Creates a new ArrayQueue with enough space to hold the given number of items before it needs to grow.
+func MakeArrayQueue(capacity int) *ArrayQueue {
+	return &ArrayQueue{makeRing(capacity)}
+}
+
+// Removes and returns the item at the front of the queue, or panics if the queue is empty.
+func (q *ArrayQueue) Dequeue() T {
+	return q.mustPop(false)
+}
+
+// Adds an item to the back of the queue.
+func (q *ArrayQueue) Enqueue(item T) {
+	q.pushBack(item)
+}
+
+func (q *ArrayQueue) Iterator() Iterator {
+	return q.iterator(false)
+}
+
+// Returns the item at the front of the queue without removing it, or panics if the queue is empty.
+func (q *ArrayQueue) Peek() T {
+	return q.mustPeek(false)
+}
+
+// Removes and returns the item at the front of the queue, if the queue is not empty.
+func (q *ArrayQueue) TryDequeue() (T, bool) {
+	return q.pop(false)
+}
+
+// Returns the item at the front of the queue without removing it, if the queue is not empty.
+func (q *ArrayQueue) TryPeek() (T, bool) {
+	return q.peek(false)
+}
+
+// A Stack is a last-in, first-out Queue backed by a slice that grows as needed. The zero value is an empty stack ready for use.
+// Iterating the stack returns the items in the order they would be popped. Enqueue, Dequeue, and Peek are synonyms for Push, Pop,
+// and Peek.
+type Stack struct {
+	ring
+}
+
+var _ Queue = &Stack{}
+
+// Creates a new Stack with enough space to hold the given number of items before it needs to grow.
+func MakeStack(capacity int) *Stack {
+	return &Stack{makeRing(capacity)}
+}
+
+// Pops an item from the stack, or panics if the stack is empty. This is a synonym for Pop.
+func (s *Stack) Dequeue() T {
+	return s.mustPop(true)
+}
+
+// Pushes an item onto the stack. This is a synonym for Push.
+func (s *Stack) Enqueue(item T) {
+	s.pushBack(item)
+}
+
+func (s *Stack) Iterator() Iterator {
+	return s.iterator(true)
+}
+
+// Returns the item at the top of the stack without removing it, or panics if the stack is empty.
+func (s *Stack) Peek() T {
+	return s.mustPeek(true)
+}
+
+// Removes and returns the item at the top of the stack, or panics if the stack is empty.
+func (s *Stack) Pop() T {
+	return s.mustPop(true)
+}
+
+// Pushes an item onto the top of the stack.
+func (s *Stack) Push(item T) {
+	s.pushBack(item)
+}
+
+// Returns the item at the top of the stack without removing it, if the stack is not empty.
+func (s *Stack) TryPeek() (T, bool) {
+	return s.peek(true)
+}
+
+// Removes and returns the item at the top of the stack, if the stack is not empty.
+func (s *Stack) TryPop() (T, bool) {
+	return s.pop(true)
+}
+
+// A Deque is a double-ended queue, backed by a circular buffer that grows as needed, that allows items to be efficiently added and
+// removed at both ends. The zero value is an empty deque ready for use. Iterating the deque returns the items from front to back.
+// When used as a Queue, items are enqueued at the back and dequeued from the front.
+type Deque struct {
+	ring
+}
+
+var _ Queue = &Deque{}
+
+// Creates a new Deque with enough space to hold the given number of items before it needs to grow.
+func MakeDeque(capacity int) *Deque {
+	return &Deque{makeRing(capacity)}
+}
+
+// Removes and returns the item at the front of the deque, or panics if the deque is empty. This is a synonym for PopFront.
+func (d *Deque) Dequeue() T {
+	return d.mustPop(false)
+}
+
+// Adds an item to the back of the deque. This is a synonym for PushBack.
+func (d *Deque) Enqueue(item T) {
+	d.pushBack(item)
+}
+
+func (d *Deque) Iterator() Iterator {
+	return d.iterator(false)
+}
+
+// Returns the item at the front of the deque without removing it, or panics if the deque is empty. This is a synonym for PeekFront.
+func (d *Deque) Peek() T {
+	return d.mustPeek(false)
+}
+
+// Returns the item at the back of the deque without removing it, or panics if the deque is empty.
+func (d *Deque) PeekBack() T {
+	return d.mustPeek(true)
+}
+
+// Returns the item at the front of the deque without removing it, or panics if the deque is empty.
+func (d *Deque) PeekFront() T {
+	return d.mustPeek(false)
+}
+
+// Removes and returns the item at the back of the deque, or panics if the deque is empty.
+func (d *Deque) PopBack() T {
+	return d.mustPop(true)
+}
+
+// Removes and returns the item at the front of the deque, or panics if the deque is empty.
+func (d *Deque) PopFront() T {
+	return d.mustPop(false)
+}
+
+// Adds an item to the back of the deque.
+func (d *Deque) PushBack(item T) {
+	d.pushBack(item)
+}
+
+// Adds an item to the front of the deque.
+func (d *Deque) PushFront(item T) {
+	d.pushFront(item)
+}
+
+// Returns the item at the back of the deque without removing it, if the deque is not empty.
+func (d *Deque) TryPeekBack() (T, bool) {
+	return d.peek(true)
+}
+
+// Returns the item at the front of the deque without removing it, if the deque is not empty.
+func (d *Deque) TryPeekFront() (T, bool) {
+	return d.peek(false)
+}
+
+// Removes and returns the item at the back of the deque, if the deque is not empty.
+func (d *Deque) TryPopBack() (T, bool) {
+	return d.pop(true)
+}
+
+// Removes and returns the item at the front of the deque, if the deque is not empty.
+func (d *Deque) TryPopFront() (T, bool) {
+	return d.pop(false)
+}
+
+// ring implements a growable circular buffer that serves as the basis for the array-based queues. It also supplies the Clear,
+// Contains, and Count methods for them.
+type ring struct {
+	items       []T
+	head, count int
+	version     int // incremented whenever the ring is modified, so iterators can detect changes
+}
+
+func makeRing(capacity int) ring {
+	if capacity < 0 {
+		panic("capacity must be non-negative")
+	}
+	return ring{items: make([]T, capacity)}
+}
+
+// Removes all items from the collection.
+func (r *ring) Clear() {
+	for i := 0; i < r.count; i++ { // clear the slots so we don't keep the items alive
+		r.items[r.index(i)] = nil
+	}
+	r.head, r.count = 0, 0
+	r.version++
+}
+
+// Indicates whether the collection contains the given item. Items are compared in the same way as MakeContainsComparer.
+func (r *ring) Contains(item T) bool {
+	cmp := makeContainsComparer(item)
+	for i := 0; i < r.count; i++ {
+		if cmp.Equal(r.items[r.index(i)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the number of items in the collection.
+func (r *ring) Count() int {
+	return r.count
+}
+
+// Converts a logical index (where zero is the front) into an index into the items array.
+func (r *ring) index(i int) int {
+	i += r.head
+	if i >= len(r.items) {
+		i -= len(r.items)
+	}
+	return i
+}
+
+func (r *ring) iterator(reverse bool) Iterator {
+	index := -1
+	if reverse {
+		index = r.count
+	}
+	return &ringIterator{r, index, r.version, reverse}
+}
+
+func (r *ring) mustPeek(back bool) T {
+	if item, ok := r.peek(back); ok {
+		return item
+	}
+	panic("the collection is empty")
+}
+
+func (r *ring) mustPop(back bool) T {
+	if item, ok := r.pop(back); ok {
+		return item
+	}
+	panic("the collection is empty")
+}
+
+func (r *ring) peek(back bool) (T, bool) {
+	if r.count == 0 {
+		return nil, false
+	} else if back {
+		return r.items[r.index(r.count-1)], true
+	} else {
+		return r.items[r.head], true
+	}
+}
+
+func (r *ring) pop(back bool) (T, bool) {
+	if r.count == 0 {
+		return nil, false
+	}
+	var index int
+	if back {
+		index = r.index(r.count - 1)
+	} else {
+		index = r.head
+		if r.head++; r.head == len(r.items) {
+			r.head = 0
+		}
+	}
+	item := r.items[index]
+	r.items[index] = nil // clear the slot so we don't keep the item alive
+	r.count--
+	r.version++
+	return item, true
+}
+
+func (r *ring) pushBack(item T) {
+	if r.count == len(r.items) {
+		r.grow()
+	}
+	r.items[r.index(r.count)] = item
+	r.count++
+	r.version++
+}
+
+func (r *ring) pushFront(item T) {
+	if r.count == len(r.items) {
+		r.grow()
+	}
+	if r.head--; r.head < 0 {
+		r.head = len(r.items) - 1
+	}
+	r.items[r.head] = item
+	r.count++
+	r.version++
+}
+
+func (r *ring) grow() {
+	capacity := len(r.items) * 2
+	if capacity == 0 {
+		capacity = 8
+	}
+	items := make([]T, capacity)
+	n := copy(items, r.items[r.head:]) // copy the items from the head to the end of the array,
+	if n < r.count {                   // and then any items that wrapped around to the beginning
+		copy(items[n:], r.items[:r.count-n])
+	}
+	r.items, r.head = items, 0
+}
+
+type ringIterator struct {
+	r              *ring
+	index, version int
+	reverse        bool
+}
+
+func (i *ringIterator) Current() T {
+	if i.version != i.r.version {
+		panic("collection was modified during iteration")
+	} else if i.index < 0 || i.index >= i.r.count {
+		panic("Current called outside sequence")
+	}
+	return i.r.items[i.r.index(i.index)]
+}
+
+func (i *ringIterator) Next() bool {
+	if i.version != i.r.version {
+		panic("collection was modified during iteration")
+	}
+	if i.reverse {
+		if i.index > 0 {
+			i.index--
+			return true
+		}
+		i.index = -1
+	} else {
+		if i.index+1 < i.r.count {
+			i.index++
+			return true
+		}
+		i.index = i.r.count
+	}
+	return false
+}
@@ -34,6 +34,18 @@ type IteratorFunc func() (T, bool)
 // A SequenceFunc represents a Sequence in a functional form.
 type SequenceFunc func() IteratorFunc
 
+// A ClosableSequenceFunc represents a Sequence in a functional form whose iterators hold resources. It returns an IteratorFunc and a
+// function that releases the resources, which will be called once when the iterator is closed or exhausted.
+type ClosableSequenceFunc func() (IteratorFunc, func() error)
+
+// A FallibleIteratorFunc represents an Iterator over a source that can fail in a functional form. It returns the next item and true,
+// or false and the error that ended the iteration (which is nil if the iteration ended normally).
+type FallibleIteratorFunc func() (T, bool, error)
+
+// A FallibleSequenceFunc represents a Sequence over a source that can fail in a functional form. It returns a FallibleIteratorFunc and
+// an optional function (which may be nil) that releases the resources held by the iterator, as with a ClosableSequenceFunc.
+type FallibleSequenceFunc func() (FallibleIteratorFunc, func() error)
+
 var sequenceCreators = make(map[reflect.Type]func(T) (Sequence, error))
 var tType = reflect.TypeOf([]T{}).Elem() // typeof(T)
 var itfType = reflect.TypeOf(IteratorFunc(nil))
@@ -52,7 +64,9 @@ func AddToSlice(slice T, seq Sequence) T {
 			copy(na, ts)
 			ts = na
 		}
-		for i := seq.Iterator(); i.Next(); {
+		i := seq.Iterator()
+		defer mustFinishIterator(i)
+		for i.Next() {
 			ts = append(ts, i.Current())
 		}
 		return ts
@@ -66,13 +80,54 @@ func AddToSlice(slice T, seq Sequence) T {
 			reflect.Copy(na, rs)
 			rs = na
 		}
-		for i := seq.Iterator(); i.Next(); {
+		i := seq.Iterator()
+		defer mustFinishIterator(i)
+		for i.Next() {
 			rs = reflect.Append(rs, reflect.ValueOf(i.Current()))
 		}
 		return rs.Interface()
 	}
 }
 
+// Closes an iterator if it's a ClosableIterator, and returns the error from Close. Otherwise, does nothing and returns nil.
+func CloseIterator(i Iterator) error {
+	if c, ok := i.(ClosableIterator); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Closes an iterator that the caller is done with and returns the error that ended the iteration, if the iterator is a
+// FallibleIterator, or else the error from closing it.
+func FinishIterator(i Iterator) error {
+	err := IteratorError(i)
+	if cerr := CloseIterator(i); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Returns the error that ended the iteration if the iterator is a FallibleIterator. Otherwise, returns nil.
+func IteratorError(i Iterator) error {
+	if f, ok := i.(FallibleIterator); ok {
+		return f.Err()
+	}
+	return nil
+}
+
+// Creates a Sequence from a ClosableSequenceFunc. Its iterators are ClosableIterators.
+func MakeClosableFunctionSequence(f ClosableSequenceFunc) Sequence {
+	return closableFunctionSequence{func() (FallibleIteratorFunc, func() error) {
+		next, close := f()
+		return infallible(next), close
+	}}
+}
+
+// Creates a Sequence from a FallibleSequenceFunc. Its iterators are ClosableIterators and FallibleIterators.
+func MakeFallibleFunctionSequence(f FallibleSequenceFunc) Sequence {
+	return closableFunctionSequence{f}
+}
+
 // Creates a Sequence from a SequenceFunc.
 func MakeFunctionSequence(f SequenceFunc) Sequence {
 	return functionSequence{f}
@@ -128,7 +183,8 @@ func ToDictionary(obj T) (Dictionary, error) {
 // Attempts to convert an object to a List using the following rules: If a sequence creator for the object type has been registered via
 // RegisterSequenceCreator, it is invoked to create a sequence, and if the sequence is a List, it is returned. Otherwise (or if the
 // sequence creator fails), if the object is a List, it is returned as-is. Otherwise, if the object is an array or slice, a generic
-// reflection-based List is created for the object. If the object is nil, a nil List is returned.
+// reflection-based List is created for the object, and if the object is a non-nil pointer to a slice, a generic reflection-based
+// MutableList is created that updates the slice in place. If the object is nil, a nil List is returned.
 func ToList(obj T) (List, error) {
 	var err error
 	t := reflect.TypeOf(obj)
@@ -147,6 +203,8 @@ func ToList(obj T) (List, error) {
 		kind := t.Kind()
 		if kind == reflect.Slice || kind == reflect.Array {
 			return genericArraySequence{reflect.ValueOf(obj)}, nil
+		} else if list, ok := toSlicePointerList(obj); ok {
+			return list, nil
 		} else if err == nil { // if we don't have an error from a sequence creator, use a generic error
 			err = fmt.Errorf("Invalid list type: %v", t)
 		}
@@ -156,10 +214,12 @@ func ToList(obj T) (List, error) {
 
 // Attempts to convert an object to a Sequence using the following rules: If a sequence creator for the object type has been registered
 // via RegisterSequenceCreator, it is invoked to create the sequence. Otherwise (or if the sequence creator fails), if the object is a
-// Sequence, it is returned as-is. Otherwise, if the object is an array, slice, map, channel, or string, a generic sequence is created
-// to iterate through the object. (Slices and arrays become Lists, maps become Dictionaries, channels become Sequences that can be
-// iterated only once, and strings iterate their runes.) Otherwise, if the object is an SequenceFunc or an IteratorFunc it is used to
-// construct a function-based sequence. If the object is nil, a nil Sequence is returned.
+// Sequence, it is returned as-is. Otherwise, if the object is an array, slice, pointer to a slice, map, channel, or string, a generic
+// sequence is created to iterate through the object. (Slices and arrays become Lists, pointers to slices become MutableLists, maps
+// become Dictionaries, channels become Sequences that can be iterated only once, and strings iterate their runes.) Otherwise, if the
+// object is an SequenceFunc or an IteratorFunc it is used to construct a function-based sequence. With Go 1.23 or later, an iter.Seq
+// (or any function with the same signature) also becomes a sequence of its items, and an iter.Seq2 becomes a sequence of Pairs. If
+// the object is nil, a nil Sequence is returned.
 func ToSequence(obj T) (Sequence, error) {
 	var err error
 	t := reflect.TypeOf(obj)
@@ -184,6 +244,8 @@ func ToSequence(obj T) (Sequence, error) {
 			return MakeOneTimeFunctionSequence(channelIterator(reflect.ValueOf(obj))), nil
 		} else if kind == reflect.String {
 			return stringSequence(obj.(string)), nil
+		} else if list, ok := toSlicePointerList(obj); ok {
+			return list, nil
 		} else if kind == reflect.Func {
 			if f, ok := obj.(func() IteratorFunc); ok { // catch all functions with the right signature, not only SequenceFunc or IteratorFunc
 				return MakeFunctionSequence(f), nil
@@ -197,6 +259,8 @@ func ToSequence(obj T) (Sequence, error) {
 				return MakeFunctionSequence(reflect.ValueOf(obj).Convert(seqfType).Interface().(SequenceFunc)), nil
 			} else if t.ConvertibleTo(itfType) {
 				return MakeOneTimeFunctionSequence(reflect.ValueOf(obj).Convert(itfType).Interface().(IteratorFunc)), nil
+			} else if seq, ok := rangeFuncSequence(obj, t); ok {
+				return seq, nil
 			}
 		}
 
@@ -207,7 +271,7 @@ func ToSequence(obj T) (Sequence, error) {
 	return nil, err
 }
 
-// Converts a Sequence to a slice of T.
+// Converts a Sequence to a slice of T. If the iteration fails, ToSlice panics with the error.
 func ToSlice(s Sequence) []T {
 	capacity := 16
 	if col, ok := s.(Collection); ok {
@@ -215,7 +279,9 @@ func ToSlice(s Sequence) []T {
 	}
 
 	items := make([]T, 0, capacity)
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
 		items = append(items, i.Current())
 	}
 	return items
@@ -228,7 +294,9 @@ func ToSliceT(s Sequence) T {
 	var t reflect.Type
 	var capacity, length int
 	initialized := false
-	for i := s.Iterator(); i.Next(); length++ {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for ; i.Next(); length++ {
 		v := i.Current()
 		if !initialized {
 			capacity = 16
@@ -260,6 +328,46 @@ func ToSliceT(s Sequence) T {
 	return array.Slice(0, length).Interface()
 }
 
+// Converts a Sequence to a slice of T, or returns the error that ended the iteration or from closing the iterator.
+func TryToSlice(s Sequence) ([]T, error) {
+	capacity := 16
+	if col, ok := s.(Collection); ok {
+		capacity = col.Count()
+	}
+
+	items := make([]T, 0, capacity)
+	i := s.Iterator()
+	defer CloseIterator(i) // close the iterator even if it panics
+	for i.Next() {
+		items = append(items, i.Current())
+	}
+	if err := FinishIterator(i); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// A FallibleIteratorFunc that returns no items.
+func endIteratorFunc() (T, bool, error) {
+	return nil, false, nil
+}
+
+// Converts an IteratorFunc into a FallibleIteratorFunc that never fails.
+func infallible(f IteratorFunc) FallibleIteratorFunc {
+	return func() (T, bool, error) {
+		item, ok := f()
+		return item, ok, nil
+	}
+}
+
+// Closes an iterator that the caller is done with and panics if the iteration ended because of an error.
+func mustFinishIterator(i Iterator) {
+	CloseIterator(i)
+	if err := IteratorError(i); err != nil {
+		panic(err)
+	}
+}
+
 // Returns an IteratorFunc that iterates over a channel.
 func channelIterator(c reflect.Value) IteratorFunc {
 	return func() (T, bool) {
@@ -272,6 +380,57 @@ func channelIterator(c reflect.Value) IteratorFunc {
 	}
 }
 
+type closableFunctionSequence struct {
+	f FallibleSequenceFunc
+}
+
+func (s closableFunctionSequence) Iterator() Iterator {
+	f, close := s.f()
+	return &closableFunctionIterator{f: f, close: close}
+}
+
+type closableFunctionIterator struct {
+	f        FallibleIteratorFunc
+	cur      T
+	valid    bool
+	close    func() error
+	err      error // the error that ended the iteration
+	closeErr error // the error from closing the iterator
+}
+
+func (i *closableFunctionIterator) Close() error {
+	i.f, i.cur, i.valid = endIteratorFunc, nil, false
+	if close := i.close; close != nil {
+		i.close = nil
+		i.closeErr = close()
+	}
+	return i.closeErr
+}
+
+func (i *closableFunctionIterator) Current() T {
+	if !i.valid {
+		panic("Current called outside sequence")
+	}
+	return i.cur
+}
+
+func (i *closableFunctionIterator) Err() error {
+	return i.err
+}
+
+func (i *closableFunctionIterator) Next() bool {
+	var err error
+	if i.cur, i.valid, err = i.f(); !i.valid {
+		if err != nil && i.err == nil {
+			i.err = err
+		}
+		if i.close != nil { // release the resources as soon as the sequence is exhausted
+			i.Close()
+		}
+	}
+	return i.valid
+}
+
 type functionSequence struct {
 	f SequenceFunc
 }
@@ -322,6 +481,17 @@ func (s genericArraySequence) IndexOf(item T) int {
 	return -1
 }
 
+// Gets the index of the first item equal to the given item according to the given equality function (e.g. DeepEqual), or -1 if no
+// such item exists.
+func (s genericArraySequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, length := 0, s.array.Len(); i < length; i++ {
+		if equal(item, s.array.Index(i).Interface()) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s genericArraySequence) Count() int {
 	return s.array.Len()
 }
@@ -352,6 +522,73 @@ func (i *genericArrayIterator) Next() bool {
 	return false
 }
 
+// genericSlicePointer implements MutableList for a pointer to a slice, updating the slice in place.
+type genericSlicePointer struct {
+	genericArraySequence // the slice element of the pointer, which is settable
+}
+
+var _ MutableList = genericSlicePointer{}
+
+func (s genericSlicePointer) Add(item T) {
+	s.array.Set(reflect.Append(s.array, s.valueOf(item)))
+}
+
+func (s genericSlicePointer) AddRange(items Sequence) {
+	s.array.Set(reflect.ValueOf(AddToSlice(s.array.Interface(), items)))
+}
+
+func (s genericSlicePointer) Clear() {
+	s.RemoveRange(0, s.array.Len())
+}
+
+func (s genericSlicePointer) Insert(index int, item T) {
+	length := s.array.Len()
+	if index < 0 || index > length {
+		panic("index out of range")
+	}
+	s.array.Set(reflect.Append(s.array, reflect.Zero(s.array.Type().Elem())))
+	reflect.Copy(s.array.Slice(index+1, length+1), s.array.Slice(index, length))
+	s.array.Index(index).Set(s.valueOf(item))
+}
+
+func (s genericSlicePointer) RemoveAt(index int) {
+	s.RemoveRange(index, 1)
+}
+
+func (s genericSlicePointer) RemoveRange(index, count int) {
+	length := s.array.Len()
+	if index < 0 || count < 0 || index+count > length {
+		panic("index out of range")
+	}
+	n := reflect.Copy(s.array.Slice(index, length), s.array.Slice(index+count, length))
+	zero := reflect.Zero(s.array.Type().Elem())
+	for i := index + n; i < length; i++ { // clear the vacated slots so we don't keep the items alive
+		s.array.Index(i).Set(zero)
+	}
+	s.array.SetLen(index + n)
+}
+
+func (s genericSlicePointer) Set(index int, value T) {
+	s.array.Index(index).Set(s.valueOf(value))
+}
+
+// Converts an item to a reflect.Value that can be stored in the slice, converting nil to the zero value.
+func (s genericSlicePointer) valueOf(item T) reflect.Value {
+	if item == nil {
+		return reflect.Zero(s.array.Type().Elem())
+	}
+	return reflect.ValueOf(item)
+}
+
+// Creates a MutableList from the object if it's a non-nil pointer to a slice.
+func toSlicePointerList(obj T) (MutableList, bool) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Slice && !v.IsNil() {
+		return genericSlicePointer{genericArraySequence{v.Elem()}}, true
+	}
+	return nil, false
+}
+
 type genericMapSequence struct {
 	m reflect.Value
 }
@@ -0,0 +1,378 @@
+/*
+adammil.net/collections is a library that implements .NET-like collection
+interfaces for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package collections
+
+import "fmt"
+
+// A SortedDictionary is a Dictionary that keeps its keys in sorted order, backed by a left-leaning red-black tree. Keys are compared
+// with a less-than function, which is GenericLessThan if not specified, and two keys are considered equal if neither is less than
+// the other. Iterating the dictionary returns Pairs in ascending order of their keys. The zero value is an empty dictionary using
+// GenericLessThan that is ready for use.
+type SortedDictionary struct {
+	root       *rbNode
+	isLessThan func(T, T) bool
+	count      int
+	version    int // incremented whenever the dictionary is modified, so iterators can detect changes
+}
+
+var _ Dictionary = &SortedDictionary{}
+
+// Creates a new SortedDictionary that orders its keys with the given less-than function (or GenericLessThan if nil).
+func MakeSortedDictionary(isLessThan func(T, T) bool) *SortedDictionary {
+	return &SortedDictionary{isLessThan: isLessThan}
+}
+
+// Returns the item whose key is the least key greater than or equal to the given key, if such an item exists.
+func (d *SortedDictionary) Ceiling(key T) (Pair, bool) {
+	var best *rbNode
+	for n := d.root; n != nil; {
+		if d.less(n.key, key) {
+			n = n.right
+		} else {
+			best = n
+			if !d.less(key, n.key) { // if the keys are equal, we're done
+				break
+			}
+			n = n.left
+		}
+	}
+	return best.pair()
+}
+
+// Removes all items from the dictionary.
+func (d *SortedDictionary) Clear() {
+	d.root, d.count = nil, 0
+	d.version++
+}
+
+// Indicates whether the dictionary contains the given Pair, comparing values with GenericEqual.
+func (d *SortedDictionary) Contains(item T) bool {
+	if p, ok := item.(Pair); ok { // a Dictionary is a sequence of Pairs so Contains(T) expects a Pair
+		if v, ok := d.TryGet(p.Key); ok { // get the value by key
+			return GenericEqual(p.Value, v) // and make sure it matches the value from the Pair
+		}
+	}
+	return false
+}
+
+// Indicates whether the dictionary contains the given key.
+func (d *SortedDictionary) ContainsKey(key T) bool {
+	return d.find(key) != nil
+}
+
+// Returns the number of items in the dictionary.
+func (d *SortedDictionary) Count() int {
+	return d.count
+}
+
+// Returns the item with the least key, if the dictionary is not empty.
+func (d *SortedDictionary) First() (Pair, bool) {
+	n := d.root
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+	return n.pair()
+}
+
+// Returns the item whose key is the greatest key less than or equal to the given key, if such an item exists.
+func (d *SortedDictionary) Floor(key T) (Pair, bool) {
+	var best *rbNode
+	for n := d.root; n != nil; {
+		if d.less(key, n.key) {
+			n = n.left
+		} else {
+			best = n
+			if !d.less(n.key, key) { // if the keys are equal, we're done
+				break
+			}
+			n = n.right
+		}
+	}
+	return best.pair()
+}
+
+// Gets a value from the dictionary given its key, and panics if the key does not exist.
+func (d *SortedDictionary) Get(key T) T {
+	if v, ok := d.TryGet(key); ok {
+		return v
+	}
+	panic(fmt.Sprintf("key '%v' not in map", key))
+}
+
+// Returns an iterator that returns the items from the dictionary as Pairs, in ascending order of their keys.
+func (d *SortedDictionary) Iterator() Iterator {
+	i := &sortedDictionaryIterator{d: d, version: d.version}
+	i.pushLeft(d.root)
+	return i
+}
+
+// Returns the item with the greatest key, if the dictionary is not empty.
+func (d *SortedDictionary) Last() (Pair, bool) {
+	n := d.root
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	return n.pair()
+}
+
+// Returns a sequence of the items whose keys are between low and high (inclusive), in ascending order of their keys.
+func (d *SortedDictionary) Range(low, high T) Sequence {
+	return MakeFunctionSequence(func() IteratorFunc {
+		i := &sortedDictionaryIterator{d: d, high: high, bounded: true, version: d.version}
+		for n := d.root; n != nil; { // push the nodes on the path to the first key >= low
+			if d.less(n.key, low) {
+				n = n.right
+			} else {
+				i.stack = append(i.stack, n)
+				n = n.left
+			}
+		}
+		return func() (T, bool) {
+			if i.Next() {
+				return i.Current(), true
+			}
+			return nil, false
+		}
+	})
+}
+
+// Removes an item from the dictionary given its key. If the key does not exist, the dictionary is unchanged.
+func (d *SortedDictionary) Remove(key T) {
+	if d.find(key) == nil {
+		return
+	}
+	if !d.root.left.isRed() && !d.root.right.isRed() {
+		d.root.red = true
+	}
+	d.root = d.delete(d.root, key)
+	if d.root != nil {
+		d.root.red = false
+	}
+	d.count--
+	d.version++
+}
+
+// Sets a value in the dictionary given its key, overwriting any existing value.
+func (d *SortedDictionary) Set(key, value T) {
+	d.root = d.insert(d.root, key, value)
+	d.root.red = false
+	d.version++
+}
+
+// Attempts to get a value from the dictionary given its key.
+func (d *SortedDictionary) TryGet(key T) (T, bool) {
+	if n := d.find(key); n != nil {
+		return n.value, true
+	}
+	return nil, false
+}
+
+func (d *SortedDictionary) delete(h *rbNode, key T) *rbNode {
+	if d.less(key, h.key) {
+		if !h.left.isRed() && !h.left.left.isRed() {
+			h = moveRedLeft(h)
+		}
+		h.left = d.delete(h.left, key)
+	} else {
+		if h.left.isRed() {
+			h = rotateRight(h)
+		}
+		if h.right == nil && d.equal(key, h.key) { // if the key matches a leaf node, remove it
+			return nil
+		}
+		if !h.right.isRed() && !h.right.left.isRed() {
+			h = moveRedRight(h)
+		}
+		if d.equal(key, h.key) { // if the key matches an internal node, replace it with its successor
+			m := h.right
+			for m.left != nil {
+				m = m.left
+			}
+			h.key, h.value = m.key, m.value
+			h.right = deleteMin(h.right)
+		} else {
+			h.right = d.delete(h.right, key)
+		}
+	}
+	return fixUp(h)
+}
+
+func (d *SortedDictionary) equal(a, b T) bool {
+	return !d.less(a, b) && !d.less(b, a)
+}
+
+func (d *SortedDictionary) find(key T) *rbNode {
+	for n := d.root; n != nil; {
+		if d.less(key, n.key) {
+			n = n.left
+		} else if d.less(n.key, key) {
+			n = n.right
+		} else {
+			return n
+		}
+	}
+	return nil
+}
+
+func (d *SortedDictionary) insert(h *rbNode, key, value T) *rbNode {
+	if h == nil {
+		d.count++
+		return &rbNode{key: key, value: value, red: true}
+	}
+	if d.less(key, h.key) {
+		h.left = d.insert(h.left, key, value)
+	} else if d.less(h.key, key) {
+		h.right = d.insert(h.right, key, value)
+	} else {
+		h.value = value
+	}
+	return fixUp(h)
+}
+
+func (d *SortedDictionary) less(a, b T) bool {
+	if d.isLessThan == nil {
+		return GenericLessThan(a, b)
+	}
+	return d.isLessThan(a, b)
+}
+
+type rbNode struct {
+	key, value  T
+	left, right *rbNode
+	red         bool
+}
+
+func (n *rbNode) isRed() bool {
+	return n != nil && n.red
+}
+
+func (n *rbNode) pair() (Pair, bool) {
+	if n == nil {
+		return Pair{}, false
+	}
+	return Pair{n.key, n.value}, true
+}
+
+func deleteMin(h *rbNode) *rbNode {
+	if h.left == nil {
+		return nil
+	}
+	if !h.left.isRed() && !h.left.left.isRed() {
+		h = moveRedLeft(h)
+	}
+	h.left = deleteMin(h.left)
+	return fixUp(h)
+}
+
+func fixUp(h *rbNode) *rbNode {
+	if h.right.isRed() && !h.left.isRed() {
+		h = rotateLeft(h)
+	}
+	if h.left.isRed() && h.left.left.isRed() {
+		h = rotateRight(h)
+	}
+	if h.left.isRed() && h.right.isRed() {
+		flipColors(h)
+	}
+	return h
+}
+
+func flipColors(h *rbNode) {
+	h.red = !h.red
+	h.left.red = !h.left.red
+	h.right.red = !h.right.red
+}
+
+func moveRedLeft(h *rbNode) *rbNode {
+	flipColors(h)
+	if h.right.left.isRed() {
+		h.right = rotateRight(h.right)
+		h = rotateLeft(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func moveRedRight(h *rbNode) *rbNode {
+	flipColors(h)
+	if h.left.left.isRed() {
+		h = rotateRight(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func rotateLeft(h *rbNode) *rbNode {
+	x := h.right
+	h.right, x.left = x.left, h
+	x.red, h.red = h.red, true
+	return x
+}
+
+func rotateRight(h *rbNode) *rbNode {
+	x := h.left
+	h.left, x.right = x.right, h
+	x.red, h.red = h.red, true
+	return x
+}
+
+type sortedDictionaryIterator struct {
+	d       *SortedDictionary
+	stack   []*rbNode
+	current *rbNode
+	high    T // the inclusive upper bound on keys, if bounded is true
+	bounded bool
+	version int
+}
+
+func (i *sortedDictionaryIterator) Current() T {
+	if i.version != i.d.version {
+		panic("collection was modified during iteration")
+	} else if i.current == nil {
+		panic("Current called outside sequence")
+	}
+	return Pair{i.current.key, i.current.value}
+}
+
+func (i *sortedDictionaryIterator) Next() bool {
+	if i.version != i.d.version {
+		panic("collection was modified during iteration")
+	}
+	i.current = nil
+	if len(i.stack) != 0 {
+		n := i.stack[len(i.stack)-1]
+		i.stack = i.stack[:len(i.stack)-1]
+		i.pushLeft(n.right)
+		if !i.bounded || !i.d.less(i.high, n.key) {
+			i.current = n
+		} else { // we've passed the upper bound, so we're done
+			i.stack = nil
+		}
+	}
+	return i.current != nil
+}
+
+// Pushes the given node and all of its left descendants onto the stack.
+func (i *sortedDictionaryIterator) pushLeft(n *rbNode) {
+	for ; n != nil; n = n.left {
+		i.stack = append(i.stack, n)
+	}
+}
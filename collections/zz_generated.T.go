@@ -71,6 +71,15 @@ func (s TSequence) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
 
+func (s TSequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s TSequence) IndexOf(item T) int {
 	cmp := makeContainsComparer(item)
 	for i, v := range s {
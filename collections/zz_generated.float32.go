@@ -71,6 +71,15 @@ func (s Float32Sequence) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
 
+func (s Float32Sequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s Float32Sequence) IndexOf(item T) int {
 	if v, ok := item.(float32); ok {
 		for i, sv := range s {
@@ -71,6 +71,15 @@ func (s IntSequence) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
 
+func (s IntSequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s IntSequence) IndexOf(item T) int {
 	if v, ok := item.(int); ok {
 		for i, sv := range s {
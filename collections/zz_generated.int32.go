@@ -71,6 +71,15 @@ func (s Int32Sequence) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
 
+func (s Int32Sequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s Int32Sequence) IndexOf(item T) int {
 	if v, ok := item.(int32); ok {
 		for i, sv := range s {
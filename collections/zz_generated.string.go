@@ -71,6 +71,15 @@ func (s StringSequence) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
 
+func (s StringSequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s StringSequence) IndexOf(item T) int {
 	if v, ok := item.(string); ok {
 		for i, sv := range s {
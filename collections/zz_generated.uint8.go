@@ -71,6 +71,15 @@ func (s Uint8Sequence) Contains(item T) bool {
 	return s.IndexOf(item) >= 0
 }
 
+func (s Uint8Sequence) IndexOfP(item T, equal func(a, b T) bool) int {
+	for i, v := range s {
+		if equal(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (s Uint8Sequence) IndexOf(item T) int {
 	if v, ok := item.(uint8); ok {
 		for i, sv := range s {
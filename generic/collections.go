@@ -0,0 +1,799 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+adammil.net/generic is a library that implements type-parameterized versions of
+the .NET-like collection interfaces and LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+// Package generic provides type-parameterized versions of the collection interfaces and LINQ queries, along with adapters that
+// convert them to and from the untyped versions in the collections and linq packages. It requires Go 1.18 or later.
+package generic
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/AdamMil/go/collections"
+)
+
+// An Iterator allows a single, forward-only traversal through a Sequence.
+type Iterator[E any] interface {
+	// Returns the current item from the iterator. This method may only be called after receiving a true result from Next(),
+	// but may be called repeatedly to retrieve the same item.
+	Current() E
+	// Advances the iterator to the next item (or, on the initial call, to the first item). Returns true if the iterator points to a
+	// valid item (and thus Current is okay to call) or false if the sequence is exhausted (and thus Current is not okay to call).
+	Next() bool
+}
+
+// A ClosableIterator is an Iterator that holds resources that should be released when the iterator is no longer needed. It is the
+// typed equivalent of collections.ClosableIterator.
+type ClosableIterator[E any] interface {
+	Iterator[E]
+	// Releases the resources held by the iterator. After Close is called, Next returns false. Close may be called more than once.
+	io.Closer
+}
+
+// A FallibleIterator is an Iterator over a source that can fail. When Next returns false, Err reports whether the iteration ended
+// because of an error. It is the typed equivalent of collections.FallibleIterator.
+type FallibleIterator[E any] interface {
+	Iterator[E]
+	// Returns the error that ended the iteration, or nil if the iteration hasn't ended or ended normally.
+	Err() error
+}
+
+// A Sequence represents a sequence of items, possibly infinite, that can be iterated. It is assumed that iterating the same
+// sequence multiple times will produce the same items each time.
+type Sequence[E any] interface {
+	// Returns an Iterator to allow iterating through the sequence.
+	Iterator() Iterator[E]
+}
+
+// A Collection represents a set of items with a finite count.
+type Collection[E any] interface {
+	Sequence[E]
+	// Indicates whether the collection contains a given item.
+	Contains(E) bool
+	// Returns the number of items in the collection.
+	Count() int
+}
+
+// A ReadOnlyDictionary represents a map from keys to values. It is also a Sequence of KeyValue objects.
+type ReadOnlyDictionary[K, V any] interface {
+	Collection[KeyValue[K, V]]
+	// Indicates whether the collection contains a given key. The Contains function from the Collection interface determines
+	// whether the dictionary contains a given KeyValue, not a given key.
+	ContainsKey(K) bool
+	// Gets a value from the dictionary given its key, and panics if the item does not exist.
+	Get(key K) V
+	// Attempts to get a value from the dictionary given its key.
+	TryGet(key K) (V, bool)
+}
+
+// A Dictionary represents a map from keys to values that can be altered. It is also a Sequence of KeyValue objects.
+type Dictionary[K, V any] interface {
+	ReadOnlyDictionary[K, V]
+	// Sets a value in the dictionary given its key, overwriting any existing value.
+	Set(key K, value V)
+	// Removes a value from the dictionary given its key.
+	Remove(key K)
+}
+
+// A ReadOnlyList represents a Collection whose items can be easily accessed in any order.
+type ReadOnlyList[E any] interface {
+	Collection[E]
+	// Gets the index of the given item, or -1 if the item doesn't exist.
+	IndexOf(item E) int
+	// Gets the item at a given index, and panics if the index is out of range.
+	Get(index int) E
+}
+
+// A List represents a Collection whose items can be easily accessed in any order and can be altered.
+type List[E any] interface {
+	ReadOnlyList[E]
+	// Sets the item at a given index, and panics if the index is out of range.
+	Set(index int, item E)
+}
+
+// A KeyValue represents a key and value. Dictionaries are sequences of KeyValues. It is the typed equivalent of collections.Pair.
+type KeyValue[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// An IteratorFunc can be used to represent an Iterator in a functional form.
+type IteratorFunc[E any] func() (E, bool)
+
+// A SequenceFunc represents a Sequence in a functional form.
+type SequenceFunc[E any] func() IteratorFunc[E]
+
+// A ClosableSequenceFunc represents a Sequence in a functional form whose iterators hold resources. It returns an IteratorFunc and a
+// function that releases the resources, which will be called once when the iterator is closed or exhausted.
+type ClosableSequenceFunc[E any] func() (IteratorFunc[E], func() error)
+
+// A FallibleIteratorFunc represents an Iterator over a source that can fail in a functional form. It returns the next item and true,
+// or false and the error that ended the iteration (which is nil if the iteration ended normally).
+type FallibleIteratorFunc[E any] func() (E, bool, error)
+
+// A FallibleSequenceFunc represents a Sequence over a source that can fail in a functional form. It returns a FallibleIteratorFunc
+// and an optional function (which may be nil) that releases the resources held by the iterator.
+type FallibleSequenceFunc[E any] func() (FallibleIteratorFunc[E], func() error)
+
+// A Map is a go map that implements Dictionary.
+type Map[K comparable, V any] map[K]V
+
+// A Slice is a go slice that implements List. Items are compared with collections.GenericEqual.
+type Slice[E any] []E
+
+var _ Dictionary[int, int] = Map[int, int]{}
+var _ List[int] = Slice[int]{}
+
+// Closes an iterator if it's a ClosableIterator, and returns the error from Close. Otherwise, does nothing and returns nil.
+func CloseIterator[E any](i Iterator[E]) error {
+	if c, ok := i.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Closes an iterator that the caller is done with and returns the error that ended the iteration, if the iterator is a
+// FallibleIterator, or else the error from closing it.
+func FinishIterator[E any](i Iterator[E]) error {
+	err := IteratorError(i)
+	if cerr := CloseIterator(i); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Returns the error that ended the iteration if the iterator is a FallibleIterator. Otherwise, returns nil.
+func IteratorError[E any](i Iterator[E]) error {
+	if f, ok := i.(interface{ Err() error }); ok {
+		return f.Err()
+	}
+	return nil
+}
+
+// Creates a Sequence from a ClosableSequenceFunc. Its iterators are ClosableIterators.
+func MakeClosableFunctionSequence[E any](f ClosableSequenceFunc[E]) Sequence[E] {
+	return closableFunctionSequence[E]{func() (FallibleIteratorFunc[E], func() error) {
+		next, close := f()
+		tryNext := func() (E, bool, error) {
+			item, ok := next()
+			return item, ok, nil
+		}
+		return tryNext, close
+	}}
+}
+
+// Creates a Sequence from a FallibleSequenceFunc. Its iterators are ClosableIterators and FallibleIterators.
+func MakeFallibleFunctionSequence[E any](f FallibleSequenceFunc[E]) Sequence[E] {
+	return closableFunctionSequence[E]{f}
+}
+
+// Creates a Sequence from a SequenceFunc.
+func MakeFunctionSequence[E any](f SequenceFunc[E]) Sequence[E] {
+	return functionSequence[E]{f}
+}
+
+// Creates a Sequence from an IteratorFunc. The sequence can only be iterated once.
+func MakeOneTimeFunctionSequence[E any](f IteratorFunc[E]) Sequence[E] {
+	done := false
+	return MakeFunctionSequence(func() IteratorFunc[E] {
+		if done {
+			panic("the sequence can only be iterated once")
+		}
+		done = true
+		return f
+	})
+}
+
+// Returns the items from the sequence in a new slice. If the iteration fails, ToSlice panics with the error.
+func ToSlice[E any](s Sequence[E]) []E {
+	var items []E
+	if c, ok := s.(Collection[E]); ok {
+		items = make([]E, 0, c.Count())
+	}
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		items = append(items, i.Current())
+	}
+	return items
+}
+
+// Returns the items from the sequence in a new slice, or the error that ended the iteration or from closing the iterator.
+func TryToSlice[E any](s Sequence[E]) ([]E, error) {
+	var items []E
+	if c, ok := s.(Collection[E]); ok {
+		items = make([]E, 0, c.Count())
+	}
+	i := s.Iterator()
+	defer CloseIterator(i) // close the iterator even if it panics
+	for i.Next() {
+		items = append(items, i.Current())
+	}
+	if err := FinishIterator(i); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Returns a typed Sequence that reads from an untyped one, converting each item to the given type. (A nil item becomes the zero value
+// of the type.) The sequence panics if an item has a different type. If the untyped sequence is a collections.List or
+// collections.Collection, the result is a List or Collection as well.
+func Typed[E any](s collections.Sequence) Sequence[E] {
+	switch s := s.(type) {
+	case untypedSequence[E]:
+		return s.s
+	case untypedCollection[E]:
+		return s.c
+	case untypedList[E]:
+		return s.l
+	case collections.List:
+		return typedList[E]{s}
+	case collections.Collection:
+		return typedCollection[E]{s}
+	default:
+		return typedSequence[E]{s}
+	}
+}
+
+// Returns a typed Dictionary that wraps an untyped one, converting keys and values to the given types. (Nil keys and values become
+// the zero values of their types.) The dictionary panics if a key or value has a different type.
+func TypedDictionary[K, V any](d collections.Dictionary) Dictionary[K, V] {
+	if u, ok := d.(untypedDictionary[K, V]); ok {
+		return u.d
+	}
+	return typedDictionary[K, V]{d}
+}
+
+// Returns a typed List that wraps an untyped one, converting each item to the given type. (A nil item becomes the zero value of the
+// type.) The list panics if an item has a different type.
+func TypedList[E any](l collections.List) List[E] {
+	if u, ok := l.(untypedList[E]); ok {
+		return u.l
+	}
+	return typedList[E]{l}
+}
+
+// Returns an untyped Sequence that reads from a typed one. If the typed sequence is a List or Collection, the result is a
+// collections.List or collections.Collection as well.
+func Untyped[E any](s Sequence[E]) collections.Sequence {
+	switch s := s.(type) {
+	case typedSequence[E]:
+		return s.s
+	case typedCollection[E]:
+		return s.c
+	case typedList[E]:
+		return s.l
+	case List[E]:
+		return untypedList[E]{s}
+	case Collection[E]:
+		return untypedCollection[E]{s}
+	default:
+		return untypedSequence[E]{s}
+	}
+}
+
+// Returns an untyped Dictionary that wraps a typed one. The dictionary is a sequence of collections.Pair objects.
+func UntypedDictionary[K, V any](d Dictionary[K, V]) collections.Dictionary {
+	if t, ok := d.(typedDictionary[K, V]); ok {
+		return t.d
+	}
+	return untypedDictionary[K, V]{d}
+}
+
+// Returns an untyped List that wraps a typed one.
+func UntypedList[E any](l List[E]) collections.List {
+	if t, ok := l.(typedList[E]); ok {
+		return t.l
+	}
+	return untypedList[E]{l}
+}
+
+func (m Map[K, V]) Contains(item KeyValue[K, V]) bool {
+	v, ok := m[item.Key]
+	return ok && collections.GenericEqual(v, item.Value)
+}
+
+func (m Map[K, V]) ContainsKey(key K) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func (m Map[K, V]) Count() int {
+	return len(m)
+}
+
+func (m Map[K, V]) Get(key K) V {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	panic(fmt.Sprintf("key '%v' not in map", key))
+}
+
+func (m Map[K, V]) Iterator() Iterator[KeyValue[K, V]] {
+	items := make([]KeyValue[K, V], 0, len(m)) // iterate a snapshot so the iterator isn't affected by later changes to the map
+	for k, v := range m {
+		items = append(items, KeyValue[K, V]{k, v})
+	}
+	return &sliceIterator[KeyValue[K, V]]{items, -1}
+}
+
+func (m Map[K, V]) Remove(key K) {
+	delete(m, key)
+}
+
+func (m Map[K, V]) Set(key K, value V) {
+	m[key] = value
+}
+
+func (m Map[K, V]) TryGet(key K) (V, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+func (s Slice[E]) Contains(item E) bool {
+	return s.IndexOf(item) >= 0
+}
+
+func (s Slice[E]) Count() int {
+	return len(s)
+}
+
+func (s Slice[E]) Get(index int) E {
+	return s[index]
+}
+
+func (s Slice[E]) IndexOf(item E) int {
+	for i, v := range s {
+		if collections.GenericEqual(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s Slice[E]) Iterator() Iterator[E] {
+	return &sliceIterator[E]{s, -1}
+}
+
+func (s Slice[E]) Set(index int, item E) {
+	s[index] = item
+}
+
+type closableFunctionSequence[E any] struct {
+	f FallibleSequenceFunc[E]
+}
+
+func (s closableFunctionSequence[E]) Iterator() Iterator[E] {
+	f, close := s.f()
+	return &closableFunctionIterator[E]{f: f, close: close}
+}
+
+type closableFunctionIterator[E any] struct {
+	f        FallibleIteratorFunc[E]
+	value    E
+	state    int // 0 = before the start, 1 = on an item, 2 = at the end
+	close    func() error
+	err      error // the error that ended the iteration
+	closeErr error // the error from closing the iterator
+}
+
+func (i *closableFunctionIterator[E]) Close() error {
+	var zero E
+	i.value, i.state = zero, 2
+	if close := i.close; close != nil {
+		i.close = nil
+		i.closeErr = close()
+	}
+	return i.closeErr
+}
+
+func (i *closableFunctionIterator[E]) Current() E {
+	if i.state != 1 {
+		panic("Current called outside sequence")
+	}
+	return i.value
+}
+
+func (i *closableFunctionIterator[E]) Err() error {
+	return i.err
+}
+
+func (i *closableFunctionIterator[E]) Next() bool {
+	if i.state != 2 {
+		var ok bool
+		var err error
+		if i.value, ok, err = i.f(); ok {
+			i.state = 1
+		} else {
+			i.state, i.err = 2, err
+			if i.close != nil { // release the resources as soon as the sequence is exhausted
+				i.Close()
+			}
+		}
+	}
+	return i.state == 1
+}
+
+type functionSequence[E any] struct {
+	f SequenceFunc[E]
+}
+
+func (s functionSequence[E]) Iterator() Iterator[E] {
+	return &functionIterator[E]{f: s.f()}
+}
+
+type functionIterator[E any] struct {
+	f     IteratorFunc[E]
+	value E
+	state int // 0 = before the start, 1 = on an item, 2 = at the end
+}
+
+func (i *functionIterator[E]) Current() E {
+	if i.state != 1 {
+		panic("Current called outside sequence")
+	}
+	return i.value
+}
+
+func (i *functionIterator[E]) Next() bool {
+	if i.state != 2 {
+		var ok bool
+		if i.value, ok = i.f(); ok {
+			i.state = 1
+		} else {
+			i.state = 2
+		}
+	}
+	return i.state == 1
+}
+
+type sliceIterator[E any] struct {
+	s     []E
+	index int
+}
+
+func (i *sliceIterator[E]) Current() E {
+	if i.index < 0 || i.index >= len(i.s) {
+		panic("Current called outside sequence")
+	}
+	return i.s[i.index]
+}
+
+func (i *sliceIterator[E]) Next() bool {
+	if i.index+1 < len(i.s) {
+		i.index++
+		return true
+	}
+	i.index = len(i.s)
+	return false
+}
+
+type typedCollection[E any] struct {
+	c collections.Collection
+}
+
+func (c typedCollection[E]) Contains(item E) bool {
+	return c.c.Contains(item)
+}
+
+func (c typedCollection[E]) Count() int {
+	return c.c.Count()
+}
+
+func (c typedCollection[E]) Iterator() Iterator[E] {
+	return typedIterator[E]{c.c.Iterator()}
+}
+
+type typedDictionary[K, V any] struct {
+	d collections.Dictionary
+}
+
+func (d typedDictionary[K, V]) Contains(item KeyValue[K, V]) bool {
+	return d.d.Contains(collections.Pair{item.Key, item.Value})
+}
+
+func (d typedDictionary[K, V]) ContainsKey(key K) bool {
+	return d.d.ContainsKey(key)
+}
+
+func (d typedDictionary[K, V]) Count() int {
+	return d.d.Count()
+}
+
+func (d typedDictionary[K, V]) Get(key K) V {
+	return fromT[V](d.d.Get(key))
+}
+
+func (d typedDictionary[K, V]) Iterator() Iterator[KeyValue[K, V]] {
+	return typedPairIterator[K, V]{d.d.Iterator()}
+}
+
+func (d typedDictionary[K, V]) Remove(key K) {
+	d.d.Remove(key)
+}
+
+func (d typedDictionary[K, V]) Set(key K, value V) {
+	d.d.Set(key, value)
+}
+
+func (d typedDictionary[K, V]) TryGet(key K) (V, bool) {
+	v, ok := d.d.TryGet(key)
+	return fromT[V](v), ok
+}
+
+type typedIterator[E any] struct {
+	i collections.Iterator
+}
+
+func (i typedIterator[E]) Close() error {
+	return collections.CloseIterator(i.i)
+}
+
+func (i typedIterator[E]) Current() E {
+	return fromT[E](i.i.Current())
+}
+
+func (i typedIterator[E]) Err() error {
+	return collections.IteratorError(i.i)
+}
+
+func (i typedIterator[E]) Next() bool {
+	return i.i.Next()
+}
+
+type typedList[E any] struct {
+	l collections.List
+}
+
+func (l typedList[E]) Contains(item E) bool {
+	return l.l.Contains(item)
+}
+
+func (l typedList[E]) Count() int {
+	return l.l.Count()
+}
+
+func (l typedList[E]) Get(index int) E {
+	return fromT[E](l.l.Get(index))
+}
+
+func (l typedList[E]) IndexOf(item E) int {
+	return l.l.IndexOf(item)
+}
+
+func (l typedList[E]) Iterator() Iterator[E] {
+	return typedIterator[E]{l.l.Iterator()}
+}
+
+func (l typedList[E]) Set(index int, item E) {
+	l.l.Set(index, item)
+}
+
+type typedPairIterator[K, V any] struct {
+	i collections.Iterator
+}
+
+func (i typedPairIterator[K, V]) Close() error {
+	return collections.CloseIterator(i.i)
+}
+
+func (i typedPairIterator[K, V]) Current() KeyValue[K, V] {
+	p := i.i.Current().(collections.Pair)
+	return KeyValue[K, V]{fromT[K](p.Key), fromT[V](p.Value)}
+}
+
+func (i typedPairIterator[K, V]) Err() error {
+	return collections.IteratorError(i.i)
+}
+
+func (i typedPairIterator[K, V]) Next() bool {
+	return i.i.Next()
+}
+
+type typedSequence[E any] struct {
+	s collections.Sequence
+}
+
+func (s typedSequence[E]) Iterator() Iterator[E] {
+	return typedIterator[E]{s.s.Iterator()}
+}
+
+type untypedCollection[E any] struct {
+	c Collection[E]
+}
+
+func (c untypedCollection[E]) Contains(item collections.T) bool {
+	v, ok := toE[E](item)
+	return ok && c.c.Contains(v)
+}
+
+func (c untypedCollection[E]) Count() int {
+	return c.c.Count()
+}
+
+func (c untypedCollection[E]) Iterator() collections.Iterator {
+	return untypedIterator[E]{c.c.Iterator()}
+}
+
+type untypedDictionary[K, V any] struct {
+	d Dictionary[K, V]
+}
+
+func (d untypedDictionary[K, V]) Contains(item collections.T) bool {
+	if p, ok := item.(collections.Pair); ok {
+		k, kok := toE[K](p.Key)
+		v, vok := toE[V](p.Value)
+		return kok && vok && d.d.Contains(KeyValue[K, V]{k, v})
+	}
+	return false
+}
+
+func (d untypedDictionary[K, V]) ContainsKey(key collections.T) bool {
+	k, ok := toE[K](key)
+	return ok && d.d.ContainsKey(k)
+}
+
+func (d untypedDictionary[K, V]) Count() int {
+	return d.d.Count()
+}
+
+func (d untypedDictionary[K, V]) Get(key collections.T) collections.T {
+	return d.d.Get(fromT[K](key))
+}
+
+func (d untypedDictionary[K, V]) Iterator() collections.Iterator {
+	return untypedPairIterator[K, V]{d.d.Iterator()}
+}
+
+func (d untypedDictionary[K, V]) Remove(key collections.T) {
+	if k, ok := toE[K](key); ok {
+		d.d.Remove(k)
+	}
+}
+
+func (d untypedDictionary[K, V]) Set(key, value collections.T) {
+	d.d.Set(fromT[K](key), fromT[V](value))
+}
+
+func (d untypedDictionary[K, V]) TryGet(key collections.T) (collections.T, bool) {
+	if k, ok := toE[K](key); ok {
+		if v, ok := d.d.TryGet(k); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type untypedIterator[E any] struct {
+	i Iterator[E]
+}
+
+func (i untypedIterator[E]) Close() error {
+	return CloseIterator(i.i)
+}
+
+func (i untypedIterator[E]) Current() collections.T {
+	return i.i.Current()
+}
+
+func (i untypedIterator[E]) Err() error {
+	return IteratorError(i.i)
+}
+
+func (i untypedIterator[E]) Next() bool {
+	return i.i.Next()
+}
+
+type untypedList[E any] struct {
+	l List[E]
+}
+
+func (l untypedList[E]) Contains(item collections.T) bool {
+	v, ok := toE[E](item)
+	return ok && l.l.Contains(v)
+}
+
+func (l untypedList[E]) Count() int {
+	return l.l.Count()
+}
+
+func (l untypedList[E]) Get(index int) collections.T {
+	return l.l.Get(index)
+}
+
+func (l untypedList[E]) IndexOf(item collections.T) int {
+	if v, ok := toE[E](item); ok {
+		return l.l.IndexOf(v)
+	}
+	return -1
+}
+
+func (l untypedList[E]) Iterator() collections.Iterator {
+	return untypedIterator[E]{l.l.Iterator()}
+}
+
+func (l untypedList[E]) Set(index int, item collections.T) {
+	l.l.Set(index, fromT[E](item))
+}
+
+type untypedPairIterator[K, V any] struct {
+	i Iterator[KeyValue[K, V]]
+}
+
+func (i untypedPairIterator[K, V]) Close() error {
+	return CloseIterator[KeyValue[K, V]](i.i)
+}
+
+func (i untypedPairIterator[K, V]) Current() collections.T {
+	kv := i.i.Current()
+	return collections.Pair{kv.Key, kv.Value}
+}
+
+func (i untypedPairIterator[K, V]) Err() error {
+	return IteratorError[KeyValue[K, V]](i.i)
+}
+
+func (i untypedPairIterator[K, V]) Next() bool {
+	return i.i.Next()
+}
+
+type untypedSequence[E any] struct {
+	s Sequence[E]
+}
+
+func (s untypedSequence[E]) Iterator() collections.Iterator {
+	return untypedIterator[E]{s.s.Iterator()}
+}
+
+// Converts an untyped value to the given type, panicking if it has a different type. Nil becomes the zero value of the type.
+func fromT[E any](v collections.T) E {
+	if v == nil {
+		var zero E
+		return zero
+	}
+	return v.(E)
+}
+
+// Closes an iterator that the caller is done with and panics if the iteration ended because of an error.
+func mustFinishIterator[E any](i Iterator[E]) {
+	CloseIterator(i)
+	if err := IteratorError(i); err != nil {
+		panic(err)
+	}
+}
+
+// Converts an untyped value to the given type, if it has that type. Nil becomes the zero value of the type if the type can be nil.
+func toE[E any](v collections.T) (E, bool) {
+	if v == nil {
+		var zero E
+		switch reflect.TypeOf(&zero).Elem().Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice, reflect.UnsafePointer:
+			return zero, true
+		default:
+			return zero, false
+		}
+	}
+	e, ok := v.(E)
+	return e, ok
+}
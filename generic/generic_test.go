@@ -0,0 +1,261 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+adammil.net/generic is a library that implements type-parameterized versions of
+the .NET-like collection interfaces and LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package generic
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AdamMil/go/collections"
+	"github.com/AdamMil/go/linq"
+)
+
+func TestAdapters(t *testing.T) {
+	t.Parallel()
+
+	// typed -> untyped
+	s := Slice[int]{1, 2, 3}
+	u := Untyped[int](s)
+	ul, ok := u.(collections.List)
+	assertTrue(t, ok, "untyped Slice is a List")
+	assertEqual(t, ul.Count(), 3)
+	assertEqual(t, ul.Get(1), 2)
+	assertEqual(t, ul.IndexOf(3), 2)
+	assertEqual(t, ul.IndexOf("3"), -1)
+	assertTrue(t, ul.Contains(1), "Contains(1)")
+	assertFalse(t, ul.Contains(nil), "Contains(nil)")
+	ul.Set(0, 4)
+	assertEqual(t, s[0], 4)
+	assertEqual(t, linq.LINQ{u}.Sum(), int64(9))
+	assertEqual(t, Typed[int](u), Sequence[int](s)) // round trips unwrap the original
+
+	_, ok = Untyped[int](MakeFunctionSequence(func() IteratorFunc[int] { return nil })).(collections.Collection)
+	assertFalse(t, ok, "untyped function sequence is a Collection")
+
+	// untyped -> typed
+	l, err := collections.ToList([]string{"a", "b"})
+	assertEqual(t, err, nil)
+	tl := TypedList[string](l)
+	assertEqual(t, tl.Get(1), "b")
+	assertEqual(t, tl.IndexOf("b"), 1)
+	tl.Set(1, "c")
+	assertEqual(t, ToSlice[string](tl), []string{"a", "c"})
+	assertEqual(t, UntypedList[string](tl), l)
+	_, ok = Typed[string](l).(List[string])
+	assertTrue(t, ok, "typed List is a List")
+	assertEqual(t, ToSlice(Typed[*int](linq.FromItems(nil).Sequence)), []*int{nil})
+	assertPanic(t, func() { ToSlice(Typed[int](linq.FromItems("x").Sequence)) }, "interface conversion")
+
+	// dictionaries
+	m := Map[string, int]{"a": 1, "b": 2}
+	d := UntypedDictionary[string, int](m)
+	assertEqual(t, d.Get("a"), 1)
+	assertTrue(t, d.Contains(collections.Pair{"b", 2}), "Contains(b, 2)")
+	assertFalse(t, d.Contains(collections.Pair{"b", 3}), "Contains(b, 3)")
+	assertFalse(t, d.ContainsKey(1), "ContainsKey(1)")
+	d.Set("c", 3)
+	d.Remove("a")
+	assertEqual(t, m, Map[string, int]{"b": 2, "c": 3})
+	assertEqual(t, linq.LINQ{d}.Select(linq.SelectPairValue).Sum(), int64(5))
+	assertEqual(t, TypedDictionary[string, int](d), Dictionary[string, int](m))
+	errs := Map[string, error]{"a": nil} // nil interface values must iterate as zero values
+	assertEqual(t, ToSlice[KeyValue[string, error]](errs), []KeyValue[string, error]{{"a", nil}})
+	assertEqual(t, UntypedDictionary[string, error](errs).Get("a"), nil)
+
+	td := TypedDictionary[string, int](collections.MakeOrderedDictionary(0))
+	td.Set("x", 1)
+	td.Set("y", 2)
+	v, ok := td.TryGet("y")
+	assertTrue(t, ok && v == 2, "TryGet(y)")
+	_, ok = td.TryGet("z")
+	assertFalse(t, ok, "TryGet(z)")
+	assertEqual(t, ToSlice[KeyValue[string, int]](td), []KeyValue[string, int]{{"x", 1}, {"y", 2}})
+	assertPanic(t, func() { m.Get("z") }, "not in map")
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	q := FromItems(5, 3, 8, 1, 3)
+	assertEqual(t, q.Count(), 5)
+	assertEqual(t, q.CountP(func(i int) bool { return i > 3 }), 2)
+	assertEqual(t, q.Where(func(i int) bool { return i&1 != 0 }).ToSlice(), []int{5, 3, 1, 3})
+	assertEqual(t, Select(q, strconv.Itoa).ToSlice(), []string{"5", "3", "8", "1", "3"})
+	assertEqual(t, SelectMany(FromItems(1, 2, 3), func(i int) Sequence[int] { return Slice[int]{i, i * 10} }).ToSlice(),
+		[]int{1, 10, 2, 20, 3, 30})
+	assertEqual(t, SelectMany(FromItems(1, 2, 3), func(i int) Sequence[int] {
+		if i == 2 {
+			return nil
+		}
+		return Slice[int]{i}
+	}).ToSlice(), []int{1, 3})
+	assertEqual(t, q.Distinct().ToSlice(), []int{5, 3, 8, 1})
+	assertEqual(t, q.Order().ToSlice(), []int{1, 3, 3, 5, 8})
+	assertEqual(t, q.OrderDescending().ToSlice(), []int{8, 5, 3, 3, 1})
+	assertEqual(t, q.Reverse().ToSlice(), []int{3, 1, 8, 3, 5})
+	assertEqual(t, q.Skip(2).Take(2).ToSlice(), []int{8, 1})
+	assertEqual(t, q.SkipWhile(func(i int) bool { return i != 8 }).ToSlice(), []int{8, 1, 3})
+	assertEqual(t, q.TakeWhile(func(i int) bool { return i != 8 }).ToSlice(), []int{5, 3})
+	assertEqual(t, q.Prepend(0).Append(9).Concat(Slice[int]{10}).ToSlice(), []int{0, 5, 3, 8, 1, 3, 9, 10})
+	assertEqual(t, q.First(), 5)
+	assertEqual(t, q.Last(), 3)
+	assertEqual(t, q.Where(func(i int) bool { return i < 5 }).Last(), 3)
+	assertEqual(t, q.Min(), 1)
+	assertEqual(t, q.Max(), 8)
+	assertEqual(t, q.MaxP(func(a, b int) bool { return a > b }), 1)
+	assertEqual(t, Sum(q), 20)
+	assertEqual(t, Sum(FromItems("a", "b")), "ab")
+	assertEqual(t, q.Aggregate(func(a, b int) int { return a * b }), 360)
+	assertEqual(t, AggregateFrom(q, "", func(s string, i int) string { return s + strconv.Itoa(i) }), "53813")
+	assertTrue(t, q.All(func(i int) bool { return i > 0 }), "All(>0)")
+	assertTrue(t, q.AnyP(func(i int) bool { return i == 8 }), "AnyP(==8)")
+	assertTrue(t, q.Contains(8), "Contains(8)")
+	assertFalse(t, q.Where(func(int) bool { return true }).Contains(9), "Contains(9)")
+	assertPanic(t, func() { q.Single() }, "too many")
+	assertEqual(t, FromItems(7).Single(), 7)
+	assertEqual(t, FromItems[int]().FirstOrDefault(-1), -1)
+	assertEqual(t, Zip(q, Slice[string]{"a", "b"}, func(i int, s string) string { return s + strconv.Itoa(i) }).ToSlice(),
+		[]string{"a5", "b3"})
+
+	_, err := FromItems[int]().TrySingle()
+	assertTrue(t, linq.IsEmptyError(err), "IsEmptyError")
+	_, err = q.TrySingle()
+	assertTrue(t, linq.IsTooManyItemsError(err), "IsTooManyItemsError")
+	assertPanic(t, func() { FromItems[int]().First() }, "empty")
+
+	// test ordering and grouping by keys
+	words := FromItems("pear", "fig", "apple", "kiwi", "plum", "date")
+	assertEqual(t, OrderBy(words, func(s string) int { return len(s) }).ToSlice(),
+		[]string{"fig", "pear", "kiwi", "plum", "date", "apple"}) // stable
+	assertEqual(t, OrderByDescending(words, func(s string) int { return len(s) }).ToSlice(),
+		[]string{"apple", "pear", "kiwi", "plum", "date", "fig"})
+	groups := GroupBy(words, func(s string) int { return len(s) }).ToSlice()
+	assertEqual(t, len(groups), 3)
+	assertEqual(t, groups[0].Key, 4)
+	assertEqual(t, groups[0].ToSlice(), []string{"pear", "kiwi", "plum", "date"})
+	assertEqual(t, groups[1].Key, 3)
+	assertEqual(t, groups[2].Count(), 1)
+	sliceGroups := GroupBy(FromItems([]int{1}, []int{2}, []int{1}), func(s []int) [1]int { return [1]int{s[0]} })
+	assertEqual(t, sliceGroups.Count(), 2)
+	m := ToMap(words, func(s string) string { return s[:1] }, func(s string) int { return len(s) })
+	assertEqual(t, m, map[string]int{"p": 4, "f": 3, "a": 5, "k": 4, "d": 4})
+	assertEqual(t, Sum(Select(FromMap(m), func(kv KeyValue[string, int]) int { return kv.Value })), 20)
+
+	// test conversion to and from LINQ
+	assertEqual(t, FromLINQ[int](linq.Range(4)).Where(func(i int) bool { return i > 1 }).ToSlice(), []int{2, 3})
+	assertEqual(t, q.LINQ().Order().ToSlice(), []collections.T{1, 3, 3, 5, 8})
+	assertEqual(t, FromLINQ[int](q.LINQ()), q)
+	assertEqual(t, FromLINQ[string](linq.From("abc").Select(func(r collections.T) collections.T { return string(r.(rune)) })).ToSlice(),
+		[]string{"a", "b", "c"})
+
+	// test function sequences and caching
+	n := 0
+	counter := FromSequenceFunction(func() IteratorFunc[int] {
+		i := 0
+		return func() (int, bool) {
+			if i < 3 {
+				i++
+				n++
+				return i, true
+			}
+			return 0, false
+		}
+	}).Cache()
+	assertEqual(t, counter.ToSlice(), []int{1, 2, 3})
+	assertEqual(t, counter.ToSlice(), []int{1, 2, 3})
+	assertEqual(t, n, 3)
+	once := FromIteratorFunction(func() (int, bool) { return 0, false })
+	assertFalse(t, once.Any(), "once.Any()")
+	assertPanic(t, func() { once.Any() }, "only be iterated once")
+
+	// test that errors from fallible sequences are passed along, including across the typed/untyped boundary
+	failure := fmt.Errorf("read failed")
+	failing := FromFallibleSequenceFunction(func() (FallibleIteratorFunc[int], func() error) { // yields 1 and 2, and then fails
+		n := 0
+		next := func() (int, bool, error) {
+			if n < 2 {
+				n++
+				return n, true, nil
+			}
+			return 0, false, failure
+		}
+		return next, nil
+	})
+	for _, s := range []Query[int]{
+		failing, failing.Where(func(i int) bool { return i > 0 }).Skip(1), failing.Order(), failing.Reverse(), failing.Cache(),
+		FromItems(1).Concat(failing), SelectMany(FromItems(1), func(int) Sequence[int] { return failing }),
+		Zip(failing, Slice[int]{1, 2, 3}, func(a, b int) int { return a + b }), FromLINQ[int](failing.LINQ().Skip(1)),
+	} {
+		_, err = s.TryToSlice()
+		assertEqual(t, err, failure)
+	}
+	_, err = GroupBy(failing, identity[int]).TryToSlice()
+	assertEqual(t, err, failure)
+	_, err = failing.TryCount()
+	assertEqual(t, err, failure)
+	_, err = TryAggregateFrom(failing, "", func(s string, i int) string { return s + strconv.Itoa(i) })
+	assertEqual(t, err, failure)
+	_, err = failing.LINQ().TryToSlice()
+	assertEqual(t, err, failure)
+	assertEqual(t, failing.Take(2).ToSlice(), []int{1, 2})
+	assertPanic(t, func() { failing.Count() }, "read failed")
+	assertPanic(t, func() { Sum(failing) }, "read failed")
+}
+
+func assertEqual(t *testing.T, actual, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v but got %v", expected, actual)
+	}
+}
+
+func assertFalse(t *testing.T, value bool, message string) {
+	t.Helper()
+	if value {
+		t.Fatal(message)
+	}
+}
+
+func assertPanic(t *testing.T, f func(), substr string) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		if v := recover(); v == nil {
+			t.Fatal("expected panic")
+		} else if s := fmt.Sprint(v); !strings.Contains(s, substr) {
+			t.Fatalf("expected panic containing %q but got %q", substr, s)
+		}
+	}()
+	f()
+}
+
+func assertTrue(t *testing.T, value bool, message string) {
+	t.Helper()
+	if !value {
+		t.Fatal(message)
+	}
+}
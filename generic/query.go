@@ -0,0 +1,867 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+adammil.net/generic is a library that implements type-parameterized versions of
+the .NET-like collection interfaces and LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package generic
+
+import (
+	"sort"
+
+	"github.com/AdamMil/go/collections"
+	"github.com/AdamMil/go/linq"
+)
+
+// A Query represents a typed Sequence that can be transformed into other sequences. It is the typed equivalent of linq.LINQ.
+// Queries that produce items of the same type are methods, while those that change the type of the items (like Select and GroupBy)
+// are functions, since Go methods can't have their own type parameters.
+type Query[E any] struct {
+	Sequence[E]
+}
+
+// A Group is a sequence of items that share a key. It is produced by GroupBy.
+type Group[K, E any] struct {
+	Key K
+	Query[E]
+}
+
+// Addable is a constraint that matches the types that can be added with the + operator: numbers and strings.
+type Addable interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~complex64 | ~complex128 | ~string
+}
+
+// Converts a Sequence into a Query that reads from it.
+func From[E any](s Sequence[E]) Query[E] {
+	if q, ok := s.(Query[E]); ok {
+		return q
+	}
+	return Query[E]{s}
+}
+
+// Converts an untyped LINQ into a Query that reads from it, converting each item to the given type. (A nil item becomes the zero value
+// of the type.) The query panics if an item has a different type.
+func FromLINQ[E any](s linq.LINQ) Query[E] {
+	return Query[E]{Typed[E](s.Sequence)}
+}
+
+// Converts a list of values into a Query that reads from the list.
+func FromItems[E any](items ...E) Query[E] {
+	return Query[E]{Slice[E](items)}
+}
+
+// Converts a map into a Query over its keys and values.
+func FromMap[K comparable, V any](m map[K]V) Query[KeyValue[K, V]] {
+	return Query[KeyValue[K, V]]{Map[K, V](m)}
+}
+
+// Creates a Query from a ClosableSequenceFunc. Its iterators are ClosableIterators that release their resources when they're closed
+// or exhausted.
+func FromClosableSequenceFunction[E any](f ClosableSequenceFunc[E]) Query[E] {
+	return Query[E]{MakeClosableFunctionSequence(f)}
+}
+
+// Creates a Query from a FallibleSequenceFunc. Its iterators are FallibleIterators that report the error that ended the iteration,
+// and ClosableIterators that release their resources (if the function provides a way to release them) when they're closed or
+// exhausted.
+func FromFallibleSequenceFunction[E any](f FallibleSequenceFunc[E]) Query[E] {
+	return Query[E]{MakeFallibleFunctionSequence(f)}
+}
+
+// Creates a Query from an IteratorFunc. The query can only be iterated once.
+func FromIteratorFunction[E any](f IteratorFunc[E]) Query[E] {
+	return Query[E]{MakeOneTimeFunctionSequence(f)}
+}
+
+// Creates a Query from a SequenceFunc.
+func FromSequenceFunction[E any](f SequenceFunc[E]) Query[E] {
+	return Query[E]{MakeFunctionSequence(f)}
+}
+
+// Applies an accumulator function to each item in the sequence, starting with the given seed, and returns the result.
+func AggregateFrom[E, A any](s Query[E], seed A, agg func(A, E) A) A {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		seed = agg(seed, i.Current())
+	}
+	return seed
+}
+
+// Groups items by the keys returned from the given selector, comparing keys with collections.GenericEqual. Groups are returned in
+// the order their keys were first seen, and the items within each group retain their original order.
+func GroupBy[E, K any](s Query[E], keySelector func(E) K) Query[Group[K, E]] {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[Group[K, E]], func() error) {
+		var groups []Group[K, E]
+		index := -1
+		next := func() (Group[K, E], bool, error) {
+			var zero Group[K, E]
+			if groups == nil { // on the first call to Next, group the items
+				var err error
+				if groups, err = groupItems(s, keySelector); err != nil {
+					return zero, false, err
+				}
+			}
+			if index+1 < len(groups) {
+				index++
+				return groups[index], true, nil
+			}
+			return zero, false, nil
+		}
+		return next, nil
+	})
+}
+
+// Returns the sequence ordered by the keys returned from the given selector, using collections.GenericLessThan to compare keys.
+// Each key is computed only once. The sort is stable, so equal items retain their original order.
+func OrderBy[E, K any](s Query[E], keySelector func(E) K) Query[E] {
+	return OrderByP(s, keySelector, nil, false)
+}
+
+// Returns the sequence ordered in reverse by the keys returned from the given selector, using collections.GenericLessThan to compare
+// keys. Each key is computed only once. The sort is stable, so equal items retain their original order.
+func OrderByDescending[E, K any](s Query[E], keySelector func(E) K) Query[E] {
+	return OrderByP(s, keySelector, nil, true)
+}
+
+// Returns the sequence ordered by the keys returned from the given selector, using the given comparison function (or
+// collections.GenericLessThan if nil). Each key is computed only once. The sort is stable, so equal items retain their original order.
+func OrderByP[E, K any](s Query[E], keySelector func(E) K, cmp func(a, b K) bool, reverse bool) Query[E] {
+	cmp = lessThanOrDefault(cmp)
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[E], func() error) {
+		var items []E
+		index, sorted := -1, false
+		next := func() (E, bool, error) {
+			var zero E
+			if !sorted { // on the first call to Next, generate and sort the data
+				var err error
+				if items, err = s.TryToSlice(); err != nil {
+					return zero, false, err
+				}
+				sorted = true
+				keys := make([]K, len(items))
+				for i, item := range items {
+					keys[i] = keySelector(item)
+				}
+				sort.Stable(keySorter[E, K]{items, keys, cmp, reverse})
+			}
+			if index+1 < len(items) {
+				index++
+				return items[index], true, nil
+			}
+			return zero, false, nil
+		}
+		return next, nil
+	})
+}
+
+// Returns the sequence with each item transformed by the given selector.
+func Select[E, R any](s Query[E], selector func(E) R) Query[R] {
+	return pipe(s, func(i Iterator[E]) IteratorFunc[R] {
+		return func() (R, bool) {
+			if i.Next() {
+				return selector(i.Current()), true
+			}
+			var zero R
+			return zero, false
+		}
+	})
+}
+
+// Returns the concatenation of the sequences returned by the given selector for each item. Nil sequences are considered empty.
+func SelectMany[E, R any](s Query[E], selector func(E) Sequence[R]) Query[R] {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[R], func() error) {
+		i := s.Iterator()
+		var inner Iterator[R]
+		next := func() (R, bool, error) {
+			var zero R
+			for {
+				if inner != nil {
+					if inner.Next() {
+						return inner.Current(), true, nil
+					}
+					CloseIterator(inner)
+					if err := IteratorError(inner); err != nil {
+						return zero, false, err
+					}
+					inner = nil
+				}
+				if !i.Next() {
+					return zero, false, IteratorError(i)
+				}
+				if seq := selector(i.Current()); seq != nil {
+					inner = seq.Iterator()
+				}
+			}
+		}
+		closeFunc := func() error {
+			err := CloseIterator(i)
+			if inner != nil {
+				if e := CloseIterator(inner); err == nil {
+					err = e
+				}
+			}
+			return err
+		}
+		return next, closeFunc
+	})
+}
+
+// Returns the sum of the items in the sequence, or the zero value if the sequence is empty. Strings are concatenated.
+func Sum[E Addable](s Query[E]) E {
+	var sum E
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		sum += i.Current()
+	}
+	return sum
+}
+
+// Adds the items in the sequence to a new map, using the given functions to select the keys and values. If multiple items have the
+// same key, the last one wins.
+func ToMap[E any, K comparable, V any](s Query[E], keySelector func(E) K, valueSelector func(E) V) map[K]V {
+	m := make(map[K]V)
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		item := i.Current()
+		m[keySelector(item)] = valueSelector(item)
+	}
+	return m
+}
+
+// Applies an accumulator function to each item in the sequence like AggregateFrom, but returns the error that ended the iteration or
+// from closing the iterator instead of panicking if the iteration fails.
+func TryAggregateFrom[E, A any](s Query[E], seed A, agg func(A, E) A) (A, error) {
+	if err := s.TryForEach(func(item E) { seed = agg(seed, item) }); err != nil {
+		var zero A
+		return zero, err
+	}
+	return seed, nil
+}
+
+// Combines the items from two sequences pairwise with the given function. The resulting sequence is as long as the shorter of
+// the two.
+func Zip[A, B, R any](a Query[A], b Sequence[B], agg func(A, B) R) Query[R] {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[R], func() error) {
+		ai, bi := a.Iterator(), b.Iterator()
+		next := func() (R, bool, error) {
+			if ai.Next() && bi.Next() {
+				return agg(ai.Current(), bi.Current()), true, nil
+			}
+			err := IteratorError(ai)
+			if err == nil {
+				err = IteratorError(bi)
+			}
+			var zero R
+			return zero, false, err
+		}
+		closeFunc := func() error {
+			if err := CloseIterator(ai); err != nil {
+				CloseIterator(bi)
+				return err
+			}
+			return CloseIterator(bi)
+		}
+		return next, closeFunc
+	})
+}
+
+// Returns the result of applying an aggregator function to the items in the sequence, or panics if the sequence is empty.
+func (s Query[E]) Aggregate(agg func(E, E) E) E {
+	if v, ok := s.TryAggregate(agg); ok {
+		return v
+	}
+	panic(errEmpty)
+}
+
+// Indicates whether the given predicate is true for all items in the sequence. If the sequence is empty, the result is true.
+func (s Query[E]) All(pred func(E) bool) bool {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		if !pred(i.Current()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Indicates whether the sequence has any items (i.e. is not empty).
+func (s Query[E]) Any() bool {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	return i.Next()
+}
+
+// Indicates whether the sequence has any items matching the given predicate. If the sequence is empty, the result is false.
+func (s Query[E]) AnyP(pred func(E) bool) bool {
+	return s.Where(pred).Any()
+}
+
+// Returns the sequence with the given items appended to it.
+func (s Query[E]) Append(items ...E) Query[E] {
+	return s.Concat(Slice[E](items))
+}
+
+// Caches the items from the sequence the first time it's iterated, to avoid excess work on repeated iterations.
+func (s Query[E]) Cache() Query[E] {
+	var items []E
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[E], func() error) {
+		index := -1
+		next := func() (E, bool, error) {
+			var zero E
+			if items == nil {
+				all, err := s.TryToSlice()
+				if err != nil {
+					return zero, false, err
+				} else if items = all; items == nil {
+					items = []E{}
+				}
+			}
+			if index+1 < len(items) {
+				index++
+				return items[index], true, nil
+			}
+			return zero, false, nil
+		}
+		return next, nil
+	})
+}
+
+// Returns the sequence with the items from the given sequences appended to it.
+func (s Query[E]) Concat(sequences ...Sequence[E]) Query[E] {
+	if len(sequences) == 0 {
+		return s
+	}
+	all := append([]Sequence[E]{s.Sequence}, sequences...)
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[E], func() error) {
+		index, i := 0, all[0].Iterator()
+		next := func() (E, bool, error) {
+			for {
+				if i.Next() {
+					return i.Current(), true, nil
+				} else if err := IteratorError(i); err != nil || index+1 == len(all) {
+					var zero E
+					return zero, false, err
+				}
+				CloseIterator(i)
+				index++
+				i = all[index].Iterator()
+			}
+		}
+		return next, func() error { return CloseIterator(i) }
+	})
+}
+
+// Indicates whether the sequence contains the given item, comparing items with collections.GenericEqual.
+func (s Query[E]) Contains(item E) bool {
+	if c, ok := s.Sequence.(Collection[E]); ok {
+		return c.Contains(item)
+	}
+	return s.ContainsP(item, nil)
+}
+
+// Indicates whether the sequence contains the given item, comparing items with the given equality function (or
+// collections.GenericEqual if nil).
+func (s Query[E]) ContainsP(item E, cmp func(a, b E) bool) bool {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		if cmp == nil && collections.GenericEqual(item, i.Current()) || cmp != nil && cmp(item, i.Current()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the number of items in the sequence.
+func (s Query[E]) Count() int {
+	if c, ok := s.Sequence.(Collection[E]); ok {
+		return c.Count()
+	}
+	n, i := 0, s.Iterator()
+	defer mustFinishIterator(i)
+	for ; i.Next(); n++ {
+	}
+	return n
+}
+
+// Returns the number of items in the sequence that match the given predicate.
+func (s Query[E]) CountP(pred func(E) bool) int {
+	return s.Where(pred).Count()
+}
+
+// Returns the sequence without duplicates, comparing items with collections.GenericEqual. Order is preserved, so the first item in
+// each set of duplicates will be included in the resulting sequence.
+func (s Query[E]) Distinct() Query[E] {
+	return pipe(s, func(i Iterator[E]) IteratorFunc[E] {
+		set := collections.MakeHashSet(0)
+		return func() (E, bool) {
+			for i.Next() {
+				if item := i.Current(); set.Add(item) {
+					return item, true
+				}
+			}
+			var zero E
+			return zero, false
+		}
+	})
+}
+
+// Returns the first item in the sequence, or panics if the sequence is empty.
+func (s Query[E]) First() E {
+	if item, ok := s.TryFirst(); ok {
+		return item
+	}
+	panic(errEmpty)
+}
+
+// Returns the first item in the sequence if it exists, or the given default otherwise.
+func (s Query[E]) FirstOrDefault(defaultValue E) E {
+	if item, ok := s.TryFirst(); ok {
+		return item
+	}
+	return defaultValue
+}
+
+// Calls the given function on each item in the sequence, and returns the sequence. The iterator is closed afterward, even if the
+// function panics.
+func (s Query[E]) ForEach(action func(E)) Query[E] {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	for i.Next() {
+		action(i.Current())
+	}
+	return s
+}
+
+// Returns the last item in the sequence, or panics if the sequence is empty.
+func (s Query[E]) Last() E {
+	if item, ok := s.TryLast(); ok {
+		return item
+	}
+	panic(errEmpty)
+}
+
+// Returns the last item in the sequence if it exists, or the given default otherwise.
+func (s Query[E]) LastOrDefault(defaultValue E) E {
+	if item, ok := s.TryLast(); ok {
+		return item
+	}
+	return defaultValue
+}
+
+// Returns the sequence as an untyped LINQ.
+func (s Query[E]) LINQ() linq.LINQ {
+	return linq.LINQ{Untyped[E](s.Sequence)}
+}
+
+// Returns the maximum item in the sequence according to collections.GenericLessThan, or panics if the sequence is empty.
+func (s Query[E]) Max() E {
+	return s.MaxP(nil)
+}
+
+// Returns the maximum item in the sequence according to the given comparison function (or collections.GenericLessThan if nil), or
+// panics if the sequence is empty.
+func (s Query[E]) MaxP(cmp func(a, b E) bool) E {
+	if v, ok := s.TryMaxP(cmp); ok {
+		return v
+	}
+	panic(errEmpty)
+}
+
+// Returns the minimum item in the sequence according to collections.GenericLessThan, or panics if the sequence is empty.
+func (s Query[E]) Min() E {
+	return s.MinP(nil)
+}
+
+// Returns the minimum item in the sequence according to the given comparison function (or collections.GenericLessThan if nil), or
+// panics if the sequence is empty.
+func (s Query[E]) MinP(cmp func(a, b E) bool) E {
+	if v, ok := s.TryMinP(cmp); ok {
+		return v
+	}
+	panic(errEmpty)
+}
+
+// Returns the sequence ordered using collections.GenericLessThan. The sort is stable, so equal items retain their original order.
+func (s Query[E]) Order() Query[E] {
+	return s.OrderP(nil)
+}
+
+// Returns the sequence ordered in reverse using collections.GenericLessThan. The sort is stable, so equal items retain their
+// original order.
+func (s Query[E]) OrderDescending() Query[E] {
+	return s.OrderDescendingP(nil)
+}
+
+// Returns the sequence ordered in reverse using the given comparison function (or collections.GenericLessThan if nil). The sort is
+// stable, so equal items retain their original order.
+func (s Query[E]) OrderDescendingP(cmp func(a, b E) bool) Query[E] {
+	return OrderByP(s, identity[E], cmp, true)
+}
+
+// Returns the sequence ordered using the given comparison function (or collections.GenericLessThan if nil). The sort is stable, so
+// equal items retain their original order.
+func (s Query[E]) OrderP(cmp func(a, b E) bool) Query[E] {
+	return OrderByP(s, identity[E], cmp, false)
+}
+
+// Returns the sequence with the given items prepended to it.
+func (s Query[E]) Prepend(items ...E) Query[E] {
+	return FromItems(items...).Concat(s.Sequence)
+}
+
+// Returns the sequence in reverse order.
+func (s Query[E]) Reverse() Query[E] {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[E], func() error) {
+		var items []E
+		index, started := 0, false
+		next := func() (E, bool, error) {
+			var zero E
+			if !started {
+				var err error
+				if items, err = s.TryToSlice(); err != nil {
+					return zero, false, err
+				}
+				index, started = len(items), true
+			}
+			if index > 0 {
+				index--
+				return items[index], true, nil
+			}
+			return zero, false, nil
+		}
+		return next, nil
+	})
+}
+
+// Returns the only item in the sequence. Panics if the sequence is empty or contains more than one item.
+func (s Query[E]) Single() E {
+	item, err := s.TrySingle()
+	if err != nil {
+		panic(err)
+	}
+	return item
+}
+
+// Returns the sequence with the given number of items removed from the front. If the number is larger than the length of the
+// sequence, the returned sequence will be empty.
+func (s Query[E]) Skip(n int) Query[E] {
+	if n == 0 {
+		return s
+	} else if n < 0 {
+		panic("argument must be non-negative")
+	}
+	return pipe(s, func(i Iterator[E]) IteratorFunc[E] {
+		skipped := false
+		return func() (E, bool) {
+			if !skipped {
+				for count := 0; count < n && i.Next(); count++ {
+				}
+				skipped = true
+			}
+			if i.Next() {
+				return i.Current(), true
+			}
+			var zero E
+			return zero, false
+		}
+	})
+}
+
+// Returns the sequence with the all items matching the given predicate removed from the front.
+func (s Query[E]) SkipWhile(pred func(E) bool) Query[E] {
+	return pipe(s, func(i Iterator[E]) IteratorFunc[E] {
+		skipped := false
+		return func() (E, bool) {
+			for i.Next() {
+				if item := i.Current(); skipped || !pred(item) {
+					skipped = true
+					return item, true
+				}
+			}
+			var zero E
+			return zero, false
+		}
+	})
+}
+
+// Returns the first N items from the sequence. If the number is larger than the length of the sequence, the entire sequence will
+// be returned.
+func (s Query[E]) Take(n int) Query[E] {
+	if n < 0 {
+		panic("argument must be non-negative")
+	}
+	return pipe(s, func(i Iterator[E]) IteratorFunc[E] {
+		count := 0
+		return func() (E, bool) {
+			if count < n && i.Next() {
+				count++
+				return i.Current(), true
+			}
+			count = n
+			var zero E
+			return zero, false
+		}
+	})
+}
+
+// Returns the items from the front of the sequence that match the given predicate.
+func (s Query[E]) TakeWhile(pred func(E) bool) Query[E] {
+	return pipe(s, func(i Iterator[E]) IteratorFunc[E] {
+		done := false
+		return func() (E, bool) {
+			if !done && i.Next() {
+				if item := i.Current(); pred(item) {
+					return item, true
+				}
+			}
+			done = true
+			var zero E
+			return zero, false
+		}
+	})
+}
+
+// Returns the items from the sequence in a new slice.
+func (s Query[E]) ToSlice() []E {
+	return ToSlice[E](s.Sequence)
+}
+
+// Returns the result of applying an aggregator function to the items in the sequence, if the sequence is not empty.
+func (s Query[E]) TryAggregate(agg func(E, E) E) (E, bool) {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	if !i.Next() {
+		var zero E
+		return zero, false
+	}
+	v := i.Current()
+	for i.Next() {
+		v = agg(v, i.Current())
+	}
+	return v, true
+}
+
+// Returns the number of items in the sequence, or the error that ended the iteration or from closing the iterator.
+func (s Query[E]) TryCount() (int, error) {
+	if c, ok := s.Sequence.(Collection[E]); ok {
+		return c.Count(), nil
+	}
+	n := 0
+	if err := s.TryForEach(func(E) { n++ }); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Returns the first item in the sequence if it exists.
+func (s Query[E]) TryFirst() (E, bool) {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	if i.Next() {
+		return i.Current(), true
+	}
+	var zero E
+	return zero, false
+}
+
+// Calls the given function on each item in the sequence, and returns the error that ended the iteration or from closing the
+// iterator. The iterator is closed afterward, even if the function panics.
+func (s Query[E]) TryForEach(action func(E)) error {
+	i := s.Iterator()
+	defer CloseIterator(i)
+	for i.Next() {
+		action(i.Current())
+	}
+	return FinishIterator(i)
+}
+
+// Returns the last item in the sequence if it exists.
+func (s Query[E]) TryLast() (E, bool) {
+	if l, ok := s.Sequence.(ReadOnlyList[E]); ok {
+		if n := l.Count(); n != 0 {
+			return l.Get(n - 1), true
+		}
+	} else {
+		i := s.Iterator()
+		defer mustFinishIterator(i)
+		if i.Next() {
+			item := i.Current()
+			for i.Next() {
+				item = i.Current()
+			}
+			return item, true
+		}
+	}
+	var zero E
+	return zero, false
+}
+
+// Returns the maximum item in the sequence according to the given comparison function (or collections.GenericLessThan if nil), if
+// the sequence is not empty.
+func (s Query[E]) TryMaxP(cmp func(a, b E) bool) (E, bool) {
+	cmp = lessThanOrDefault(cmp)
+	return s.TryAggregate(func(a, b E) E {
+		if cmp(a, b) {
+			return b
+		}
+		return a
+	})
+}
+
+// Returns the minimum item in the sequence according to the given comparison function (or collections.GenericLessThan if nil), if
+// the sequence is not empty.
+func (s Query[E]) TryMinP(cmp func(a, b E) bool) (E, bool) {
+	cmp = lessThanOrDefault(cmp)
+	return s.TryAggregate(func(a, b E) E {
+		if cmp(b, a) {
+			return b
+		}
+		return a
+	})
+}
+
+// Returns the only item in the sequence. Returns an error if the sequence is empty or contains more than one item. The error can
+// be identified with linq.IsEmptyError and linq.IsTooManyItemsError.
+func (s Query[E]) TrySingle() (E, error) {
+	i := s.Iterator()
+	defer mustFinishIterator(i)
+	if !i.Next() {
+		var zero E
+		return zero, errEmpty
+	}
+	item := i.Current()
+	if i.Next() {
+		var zero E
+		return zero, errTooMany
+	}
+	return item, nil
+}
+
+// Returns the items from the sequence in a new slice, or the error that ended the iteration or from closing the iterator.
+func (s Query[E]) TryToSlice() ([]E, error) {
+	return TryToSlice[E](s.Sequence)
+}
+
+// Returns the sequence with only the items that match the given predicate.
+func (s Query[E]) Where(pred func(E) bool) Query[E] {
+	return pipe(s, func(i Iterator[E]) IteratorFunc[E] {
+		return func() (E, bool) {
+			for i.Next() {
+				if item := i.Current(); pred(item) {
+					return item, true
+				}
+			}
+			var zero E
+			return zero, false
+		}
+	})
+}
+
+// The errors used by the linq package, so that linq.IsEmptyError and linq.IsTooManyItemsError recognize the errors returned here.
+var errEmpty, errTooMany = singleError(linq.Empty), singleError(linq.FromItems(nil, nil))
+
+func groupItems[E, K any](s Query[E], keySelector func(E) K) ([]Group[K, E], error) {
+	indices := collections.MakeOrderedDictionary(0) // map keys to their indices within the groups
+	var keys []K
+	var items [][]E
+	i := s.Iterator()
+	defer CloseIterator(i) // close the iterator even if the key selector panics
+	for i.Next() {
+		item := i.Current()
+		key := keySelector(item)
+		if index, ok := indices.TryGet(key); ok {
+			items[index.(int)] = append(items[index.(int)], item)
+		} else {
+			indices.Set(key, len(keys))
+			keys, items = append(keys, key), append(items, []E{item})
+		}
+	}
+	if err := FinishIterator(i); err != nil {
+		return nil, err
+	}
+	groups := make([]Group[K, E], len(keys))
+	for i := range groups {
+		groups[i] = Group[K, E]{keys[i], FromItems(items[i]...)}
+	}
+	return groups, nil
+}
+
+func identity[E any](item E) E {
+	return item
+}
+
+func lessThanOrDefault[E any](cmp func(a, b E) bool) func(a, b E) bool {
+	if cmp == nil {
+		cmp = func(a, b E) bool { return collections.GenericLessThan(a, b) }
+	}
+	return cmp
+}
+
+// Returns a sequence whose iterators get items from the IteratorFunc that the given function returns for an iterator over the
+// source. The source iterator is closed when the returned iterator is closed or exhausted, and if the source iteration fails, the
+// returned iterator fails with the same error.
+func pipe[E, R any](s Query[E], f func(Iterator[E]) IteratorFunc[R]) Query[R] {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc[R], func() error) {
+		i := s.Iterator()
+		next := f(i)
+		tryNext := func() (R, bool, error) {
+			item, ok := next()
+			if !ok {
+				return item, false, IteratorError(i)
+			}
+			return item, true, nil
+		}
+		return tryNext, func() error { return CloseIterator(i) }
+	})
+}
+
+func singleError(s linq.LINQ) error {
+	_, err := s.TrySingle()
+	return err
+}
+
+type keySorter[E, K any] struct {
+	items   []E
+	keys    []K
+	cmp     func(a, b K) bool
+	reverse bool
+}
+
+func (s keySorter[E, K]) Len() int {
+	return len(s.items)
+}
+
+func (s keySorter[E, K]) Less(i, j int) bool {
+	if s.reverse {
+		return s.cmp(s.keys[j], s.keys[i])
+	}
+	return s.cmp(s.keys[i], s.keys[j])
+}
+
+func (s keySorter[E, K]) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
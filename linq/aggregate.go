@@ -92,6 +92,7 @@ func (s LINQ) AggregateOrNilR(agg T) T {
 // sequence is empty, the function returns nil and a false value.
 func (s LINQ) TryAggregate(agg Aggregator) (T, bool) {
 	i := s.Iterator()
+	defer finishIterators(i)
 	if !i.Next() {
 		return nil, false
 	}
@@ -111,11 +112,30 @@ func (s LINQ) TryAggregateR(agg T) (T, bool) {
 	return s.TryAggregate(genericAggregatorFunc(agg))
 }
 
+// Aggregates items from the sequence like AggregateOrDefault, but returns the error that ended the iteration or from closing the
+// iterator instead of panicking if the iteration fails.
+func (s LINQ) TryAggregateOrDefault(defaultValue T, agg Aggregator) (T, error) {
+	v, first := defaultValue, true
+	err := s.TryForEach(func(item T) {
+		if first {
+			v, first = item, false
+		} else {
+			v = agg(v, item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 // Aggregates items from the sequence. The given seed and the first item are passed to the aggregator function, then the result and
 // the second item are passed to the function, and so on. The final return value from the function is returned. However, if the
 // sequence is empty, the seed is returned.
 func (s LINQ) AggregateFrom(seed T, agg Aggregator) T {
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		seed = agg(seed, i.Current())
 	}
 	return seed
@@ -129,6 +149,15 @@ func (s LINQ) AggregateFromR(seed T, agg T) T {
 	return s.AggregateFrom(seed, genericAggregatorFunc(agg))
 }
 
+// Aggregates items from the sequence like AggregateFrom, but returns the error that ended the iteration or from closing the
+// iterator instead of panicking if the iteration fails.
+func (s LINQ) TryAggregateFrom(seed T, agg Aggregator) (T, error) {
+	if err := s.TryForEach(func(item T) { seed = agg(seed, item) }); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
 // Returns the item from the sequence with the greatest value according to the default comparison function, or if the sequence is
 // empty the function panics.
 func (s LINQ) Max() T {
@@ -231,11 +260,17 @@ func (s LINQ) MergeP(rs Sequence, cmp LessThanFunc, leftOnly func(T) (T, bool),
 	if cmp == nil {
 		cmp = GenericLessThan
 	}
-	return FromSequenceFunction(func() IteratorFunc {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		li, ri := s.Iterator(), rs.Iterator()
 		ln, rn := li.Next(), ri.Next()
-		return func() (T, bool) {
+		next := func() (T, bool, error) {
 			for {
+				if !ln || !rn { // if either side has ended, make sure it didn't fail
+					if err := iteratorsError(li, ri); err != nil {
+						return nil, false, err
+					}
+				}
+
 				var nv T
 				var keep bool
 				if ln && rn { // if we have values from both sides...
@@ -263,14 +298,15 @@ func (s LINQ) MergeP(rs Sequence, cmp LessThanFunc, leftOnly func(T) (T, bool),
 					nv, keep = rightOnly(ri.Current())
 					rn = ri.Next()
 				} else { // we have no values (or no functions to receive the values)
-					return nil, false
+					return nil, false, nil
 				}
 
 				if keep { // if the new value should be included in the sequence...
-					return nv, true // return it
+					return nv, true, nil // return it
 				} // otherwise, loop around to the next value
 			}
 		}
+		return next, func() error { return closeIterators(li, ri) }
 	})
 }
 
@@ -379,8 +415,10 @@ func (s LINQ) TryMinR(cmp T) (T, bool) {
 }
 
 // Returns the sum of the items in the sequence. Most numeric values can be added together, although signed and unsigned integers
-// cannot. A sequence of strings will be concatenated. The result will always be normalized into either an int64, uint64, float64,
-// complex128, or string. If the sequence is empty, the function panics.
+// cannot. A sequence of strings will be concatenated. The result will be normalized into either an int64, uint64, float64,
+// complex128, or string, unless all items have the same named type (e.g. type Age int), in which case it will have that type. A
+// sum of a named type is not widened, so it wraps around if it overflows (e.g. the sum of 200 and 100 with type Age uint8 is 44).
+// If the sequence is empty, the function panics.
 func (s LINQ) Sum() T {
 	return normalizeSum(s.Aggregate(genericAdd))
 }
@@ -393,8 +431,10 @@ func (s LINQ) SumFrom(seed T) T {
 }
 
 // Returns the sum of the items in the sequence. Most numeric values can be added together, although signed and unsigned integers
-// cannot. A sequence of strings will be concatenated. The result will always be normalized into either an int64, uint64, float64,
-// complex128, or string. If the sequence is empty, the function returns the given default (without normalizing it).
+// cannot. A sequence of strings will be concatenated. The result will be normalized into either an int64, uint64, float64,
+// complex128, or string, unless all items have the same named type (e.g. type Age int), in which case it will have that type. A
+// sum of a named type is not widened, so it wraps around if it overflows (e.g. the sum of 200 and 100 with type Age uint8 is 44).
+// If the sequence is empty, the function returns the given default (without normalizing it).
 func (s LINQ) SumOrDefault(defaultValue T) T {
 	if sum, ok := s.TryAggregate(genericAdd); ok {
 		return sum
@@ -403,15 +443,19 @@ func (s LINQ) SumOrDefault(defaultValue T) T {
 }
 
 // Returns the sum of the items in the sequence. Most numeric values can be added together, although signed and unsigned integers
-// cannot. A sequence of strings will be concatenated. The result will always be normalized into either an int64, uint64, float64,
-// complex128, or string. If the sequence is empty, the function returns nil.
+// cannot. A sequence of strings will be concatenated. The result will be normalized into either an int64, uint64, float64,
+// complex128, or string, unless all items have the same named type (e.g. type Age int), in which case it will have that type. A
+// sum of a named type is not widened, so it wraps around if it overflows (e.g. the sum of 200 and 100 with type Age uint8 is 44).
+// If the sequence is empty, the function returns nil.
 func (s LINQ) SumOrNil() T {
 	return s.SumOrDefault(nil)
 }
 
 // Returns the sum of the items in the sequence. Most numeric values can be added together, although signed and unsigned integers
-// cannot. A sequence of strings will be concatenated. The result will always be normalized into either an int64, uint64, float64,
-// complex128, or string. If the sequence is empty, the function returns a false value to indicate failure.
+// cannot. A sequence of strings will be concatenated. The result will be normalized into either an int64, uint64, float64,
+// complex128, or string, unless all items have the same named type (e.g. type Age int), in which case it will have that type. A
+// sum of a named type is not widened, so it wraps around if it overflows (e.g. the sum of 200 and 100 with type Age uint8 is 44).
+// If the sequence is empty, the function returns a false value to indicate failure.
 func (s LINQ) TrySum() (T, bool) {
 	sum, ok := s.TryAggregate(genericAdd)
 	if ok {
@@ -423,36 +467,36 @@ func (s LINQ) TrySum() (T, bool) {
 // Combines each tuple of items from several sequences by passing them to an aggregator function. The resulting sequence is returned,
 // and is the length of the shortest input sequence.
 func Zip(agg func([]T) T, seqs ...Sequence) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		params, iters := make([]T, len(seqs)), make([]Iterator, len(seqs))
 		for i := 0; i < len(iters); i++ {
 			iters[i] = seqs[i].Iterator()
 		}
-		return func() (T, bool) {
-			for {
-				for i := 0; i < len(iters); i++ {
-					if !iters[i].Next() {
-						return nil, false
-					}
-					params[i] = iters[i].Current()
+		next := func() (T, bool, error) {
+			for i := 0; i < len(iters); i++ {
+				if !iters[i].Next() {
+					return nil, false, IteratorError(iters[i])
 				}
-				return agg(params), true
+				params[i] = iters[i].Current()
 			}
+			return agg(params), true, nil
 		}
+		return next, func() error { return closeIterators(iters...) }
 	})
 }
 
 // Combines each pair of items from two sequences by passing them to an aggregator function. The resulting sequence is returned,
 // and is the length of the shortest input sequence.
 func (s LINQ) Zip(sequence Sequence, agg Aggregator) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		i1, i2 := s.Iterator(), sequence.Iterator()
-		return func() (T, bool) {
+		next := func() (T, bool, error) {
 			if i1.Next() && i2.Next() {
-				return agg(i1.Current(), i2.Current()), true
+				return agg(i1.Current(), i2.Current()), true, nil
 			}
-			return nil, false
+			return nil, false, iteratorsError(i1, i2)
 		}
+		return next, func() error { return closeIterators(i1, i2) }
 	})
 }
 
@@ -468,7 +512,16 @@ func genericAdd(a, b T) T {
 		if b == nil {
 			return a
 		}
-		ka = reflect.TypeOf(a).Kind()
+		ta := reflect.TypeOf(a)
+		ua, namedA := toBuiltinType(a)
+		ub, namedB := toBuiltinType(b)
+		if namedA || namedB { // if either value has a named type...
+			if ta == reflect.TypeOf(b) { // if they have the same named type, convert the sum back to that type
+				return reflect.ValueOf(genericAdd(ua, ub)).Convert(ta).Interface()
+			}
+			a, b, ta = ua, ub, reflect.TypeOf(ua) // otherwise, add them as though they had their underlying types
+		}
+		ka = ta.Kind()
 	}
 	switch ka {
 	case reflect.Invalid: // a is nil
@@ -639,6 +692,28 @@ func stringAdd(a string, b T) T {
 	panic(fmt.Sprintf("type %T cannot be added to string", b))
 }
 
+// Converts a value of a named boolean, numeric, or string type (e.g. type Age int) to its underlying built-in type, and returns
+// true. Other values are returned as-is, with false.
+func toBuiltinType(v T) (T, bool) {
+	if t := reflect.TypeOf(v); t != nil {
+		if k := t.Kind(); int(k) < len(builtinTypes) && builtinTypes[k] != nil && t != builtinTypes[k] {
+			return reflect.ValueOf(v).Convert(builtinTypes[k]).Interface(), true
+		}
+	}
+	return v, false
+}
+
+// the built-in types for each boolean, numeric, and string kind, indexed by kind
+var builtinTypes = [...]reflect.Type{
+	reflect.Bool: reflect.TypeOf(false), reflect.Int: reflect.TypeOf(int(0)), reflect.Int8: reflect.TypeOf(int8(0)),
+	reflect.Int16: reflect.TypeOf(int16(0)), reflect.Int32: reflect.TypeOf(int32(0)), reflect.Int64: reflect.TypeOf(int64(0)),
+	reflect.Uint: reflect.TypeOf(uint(0)), reflect.Uint8: reflect.TypeOf(uint8(0)), reflect.Uint16: reflect.TypeOf(uint16(0)),
+	reflect.Uint32: reflect.TypeOf(uint32(0)), reflect.Uint64: reflect.TypeOf(uint64(0)), reflect.Uintptr: reflect.TypeOf(uintptr(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)), reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.Complex64: reflect.TypeOf(complex64(0)), reflect.Complex128: reflect.TypeOf(complex128(0)),
+	reflect.String: reflect.TypeOf(""),
+}
+
 func max(a, b T) T {
 	if GenericLessThan(a, b) {
 		return b
@@ -676,7 +751,7 @@ func minf(isLessThan LessThanFunc) Aggregator {
 }
 
 func normalizeSum(v T) T {
-	if v != nil {
+	if _, named := toBuiltinType(v); v != nil && !named { // values of named types aren't normalized
 		switch reflect.TypeOf(v).Kind() {
 		case reflect.Int:
 			v = int64(v.(int))
@@ -49,22 +49,27 @@ func (s LINQ) Prepend(items ...T) LINQ {
 }
 
 func concatSequence(seq Sequence, sequences []Sequence) Sequence {
-	return MakeFunctionSequence(func() IteratorFunc {
+	return MakeFallibleFunctionSequence(func() (FallibleIteratorFunc, func() error) {
 		iter, index := seq.Iterator(), 0
-		return func() (T, bool) {
+		next := func() (T, bool, error) {
 			for {
 				if iter == nil { // if we need a new iterator...
 					if index >= len(sequences) { // but there aren't any left...
-						return nil, false // we're at the end
+						return nil, false, nil // we're at the end
 					}
 					iter = sequences[index].Iterator() // otherwise, get the next iterator
 					index++
 				}
 				if iter.Next() { // if the current iterator has an item, return true
-					return iter.Current(), true
+					return iter.Current(), true, nil
+				}
+				CloseIterator(iter)
+				if err := IteratorError(iter); err != nil { // if the current iterator failed, so does the concatenation
+					return nil, false, err
 				}
 				iter = nil // otherwise, the current is empty, so clear it and get the next one
 			}
 		}
+		return next, func() error { return closeIterators(iter) }
 	})
 }
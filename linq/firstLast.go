@@ -77,7 +77,9 @@ func (s LINQ) FirstOrNilR(pred T) T {
 
 // Returns the first item in the sequence if it exists.
 func (s LINQ) TryFirst() (T, bool) {
-	if i := s.Iterator(); i.Next() {
+	i := s.Iterator()
+	defer finishIterators(i)
+	if i.Next() {
 		return i.Current(), true
 	}
 	return nil, false
@@ -150,7 +152,9 @@ func (s LINQ) LastOrNilR(pred T) T {
 
 // Returns the last item in the sequence if it exists.
 func (s LINQ) TryLast() (T, bool) {
-	if i := s.Iterator(); i.Next() {
+	i := s.Iterator()
+	defer finishIterators(i)
+	if i.Next() {
 		var item T
 		for {
 			item = i.Current()
@@ -196,7 +200,9 @@ func (s LINQ) SingleR(pred T) T {
 // Returns the first item in the sequence, or returns the given default if the sequence is empty, or panics if the sequence has
 // multiple items.
 func (s LINQ) SingleOrDefault(defaultValue T) T {
-	if i := s.Iterator(); i.Next() {
+	i := s.Iterator()
+	defer finishIterators(i)
+	if i.Next() {
 		v := i.Current()
 		if !i.Next() {
 			return v
@@ -237,7 +243,9 @@ func (s LINQ) SingleOrNilR(pred T) T {
 
 // Returns the first item in the sequence or an error if the sequence is empty or has multiple items.
 func (s LINQ) TrySingle() (T, error) {
-	if i := s.Iterator(); i.Next() {
+	i := s.Iterator()
+	defer finishIterators(i)
+	if i.Next() {
 		v := i.Current()
 		if !i.Next() {
 			return v, nil
@@ -45,6 +45,19 @@ type Predicate func(T) bool
 // A Selector converts an item into another item. It is a func(T) T.
 type Selector func(T) T
 
+// Creates a LINQ object from a ClosableSequenceFunc. Its iterators are ClosableIterators that release their resources when they're
+// closed or exhausted.
+func FromClosableSequenceFunction(f ClosableSequenceFunc) LINQ {
+	return LINQ{MakeClosableFunctionSequence(f)}
+}
+
+// Creates a LINQ object from a FallibleSequenceFunc. Its iterators are FallibleIterators that report the error that ended the
+// iteration, and ClosableIterators that release their resources (if the function provides a way to release them) when they're
+// closed or exhausted.
+func FromFallibleSequenceFunction(f FallibleSequenceFunc) LINQ {
+	return LINQ{MakeFallibleFunctionSequence(f)}
+}
+
 // Creates a LINQ object from a IteratorFunc. The sequence can only be iterated once.
 func FromIteratorFunction(f IteratorFunc) LINQ {
 	return LINQ{MakeOneTimeFunctionSequence(f)}
@@ -177,9 +190,15 @@ func genericAggregatorFunc(f T) Aggregator { // see above for comments
 	} else if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 1 {
 		panic(fmt.Sprintf("called with non-aggregator %v", f))
 	}
-	v := reflect.ValueOf(f)
+	v, ta, tb := reflect.ValueOf(f), t.In(0), t.In(1)
 	return func(a, b T) T {
 		va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+		if a == nil { // pass nils (such as the missing items from outer joins) as zero values
+			va = reflect.Zero(ta)
+		}
+		if b == nil {
+			vb = reflect.Zero(tb)
+		}
 		return v.Call([]reflect.Value{va, vb})[0].Interface()
 	}
 }
@@ -0,0 +1,41 @@
+//go:build go1.23
+// +build go1.23
+
+/*
+adammil.net/linq is a library that implements .NET-like LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package linq
+
+import (
+	"iter"
+
+	. "github.com/AdamMil/go/collections"
+)
+
+// Returns an iter.Seq that yields the items from the sequence, so the query can be consumed directly with a range loop.
+// (The method isn't called All because All is the universal quantifier.)
+func (s LINQ) Items() iter.Seq[T] {
+	return Items(s.Sequence)
+}
+
+// Returns an iter.Seq2 that yields the keys and values of the Pairs in the sequence, so the query can be consumed directly with a
+// range loop. Panics if an item is not a Pair.
+func (s LINQ) Pairs() iter.Seq2[T, T] {
+	return Pairs(s.Sequence)
+}
@@ -0,0 +1,75 @@
+//go:build go1.23
+// +build go1.23
+
+/*
+adammil.net/linq is a library that implements .NET-like LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package linq
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+
+	. "github.com/AdamMil/go/collections"
+)
+
+type intSeq func(func(int) bool) // a named type that's not iter.Seq
+
+func TestLinqRangeFunc(t *testing.T) {
+	t.Parallel()
+
+	// test conversion of iter.Seq and iter.Seq2 to sequences
+	assertLinqEqual(t, From(slices.Values([]int{1, 2, 3})), 1, 2, 3)
+	assertLinqEqual(t, From(slices.Values([]int{1, 2, 3})).Select(func(i T) T { return i.(int) * 2 }), 2, 4, 6)
+	assertLinqEqual(t, From(iter.Seq[T](func(yield func(T) bool) { _ = yield(1) && yield("a") })), 1, "a")
+	assertLinqEqual(t, From(func(yield func(T, T) bool) { yield(1, 2) }), Pair{1, 2})
+	assertLinqEqual(t, From(slices.All([]string{"a", "b"})), Pair{0, "a"}, Pair{1, "b"})
+	assertLinqEqual(t, From(maps.All(map[string]int{"x": 1})), Pair{"x", 1})
+	count := intSeq(func(yield func(int) bool) {
+		for i := 0; i < 1000 && yield(i); i++ {
+		}
+	})
+	assertLinqEqual(t, From(count).Take(3), 0, 1, 2)
+	assertLinqEqual(t, From(count).Skip(998), 998, 999)
+	assertEqual(t, From(count).Count(), 1000) // sequences can be iterated more than once
+	_, err := TryFrom(func(int) bool { return true })
+	assertTrue(t, err != nil, "func(int) bool is a sequence")
+
+	// test consuming queries with range loops
+	var items []T
+	for item := range Range(10).Where(func(i T) bool { return i.(int)&1 == 0 }).Items() {
+		if item.(int) > 4 {
+			break
+		}
+		items = append(items, item)
+	}
+	assertSlicesEqual(t, items, 0, 2, 4)
+
+	sum := 0
+	for k, v := range From(map[int]int{1: 10, 2: 20}).Pairs() {
+		sum += k.(int) + v.(int)
+	}
+	assertEqual(t, sum, 33)
+	assertPanic(t, func() {
+		for range FromItems(1).Pairs() {
+		}
+	}, "not collections.Pair")
+}
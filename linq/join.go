@@ -0,0 +1,180 @@
+/*
+adammil.net/linq is a library that implements .NET-like LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package linq
+
+import . "github.com/AdamMil/go/collections"
+
+// Returns the sequence with each item (from the "outer" sequence) paired with each item from the "inner" sequence that has an equal
+// key (comparing keys with GenericEqual). The pairs are passed to the result selector, which returns the items of the resulting
+// sequence. The order of the outer sequence is preserved, and the inner items matching each outer item are returned in the order of
+// the inner sequence. Outer items with no matching inner items are omitted.
+func (s LINQ) Join(inner Sequence, outerKey, innerKey Selector, resultSelector Aggregator) LINQ {
+	return s.join(inner, outerKey, innerKey, resultSelector, false, false)
+}
+
+// Returns the sequence with each item (from the "outer" sequence) paired with each item from the "inner" sequence that has an equal
+// key (comparing keys with GenericEqual). The pairs are passed to the result selector, which returns the items of the resulting
+// sequence. Outer items with no matching inner items are omitted. If any function is strongly typed, it will be called via
+// reflection.
+func (s LINQ) JoinR(inner Sequence, outerKey, innerKey, resultSelector T) LINQ {
+	return s.Join(inner, genericSelectorFunc(outerKey), genericSelectorFunc(innerKey), genericAggregatorFunc(resultSelector))
+}
+
+// Returns the sequence with each item (from the "outer" sequence) paired with each item from the "inner" sequence that has an equal
+// key (comparing keys with GenericEqual) like Join, but outer items with no matching inner items are passed to the result selector
+// along with nil.
+func (s LINQ) LeftJoin(inner Sequence, outerKey, innerKey Selector, resultSelector Aggregator) LINQ {
+	return s.join(inner, outerKey, innerKey, resultSelector, true, false)
+}
+
+// Returns the sequence with each item (from the "outer" sequence) paired with each item from the "inner" sequence that has an equal
+// key (comparing keys with GenericEqual) like Join, but outer items with no matching inner items are passed to the result selector
+// along with nil (or the zero value of a strongly typed parameter). If any function is strongly typed, it will be called via
+// reflection.
+func (s LINQ) LeftJoinR(inner Sequence, outerKey, innerKey, resultSelector T) LINQ {
+	return s.LeftJoin(inner, genericSelectorFunc(outerKey), genericSelectorFunc(innerKey), genericAggregatorFunc(resultSelector))
+}
+
+// Returns the sequence with each item (from the "outer" sequence) paired with each item from the "inner" sequence that has an equal
+// key (comparing keys with GenericEqual) like LeftJoin, but after the outer sequence has been processed, the inner items that
+// matched no outer item are passed to the result selector along with nil (as the outer item), in the order of the inner sequence.
+func (s LINQ) FullOuterJoin(inner Sequence, outerKey, innerKey Selector, resultSelector Aggregator) LINQ {
+	return s.join(inner, outerKey, innerKey, resultSelector, true, true)
+}
+
+// Returns the sequence with each item (from the "outer" sequence) paired with each item from the "inner" sequence that has an equal
+// key (comparing keys with GenericEqual) like LeftJoin, but after the outer sequence has been processed, the inner items that
+// matched no outer item are passed to the result selector along with nil (or the zero value of a strongly typed parameter).
+// If any function is strongly typed, it will be called via reflection.
+func (s LINQ) FullOuterJoinR(inner Sequence, outerKey, innerKey, resultSelector T) LINQ {
+	return s.FullOuterJoin(inner, genericSelectorFunc(outerKey), genericSelectorFunc(innerKey), genericAggregatorFunc(resultSelector))
+}
+
+// Returns the sequence with each item (from the "outer" sequence) passed to the result selector along with a sequence of the items
+// from the "inner" sequence that have an equal key (comparing keys with GenericEqual), which may be empty. The result selector
+// returns the items of the resulting sequence. The order of both sequences is preserved.
+func (s LINQ) GroupJoin(inner Sequence, outerKey, innerKey Selector, resultSelector Aggregator) LINQ {
+	var lookup *joinLookup
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		iter := s.Iterator()
+		next := func() (T, bool, error) {
+			if lookup == nil { // on the first call to Next, group the inner sequence by key
+				var err error
+				if lookup, err = makeJoinLookup(inner, innerKey); err != nil {
+					return nil, false, err
+				}
+			}
+			if !iter.Next() {
+				return nil, false, IteratorError(iter)
+			}
+			item, matches := iter.Current(), Empty
+			if list, ok := lookup.groups.TryGet(outerKey(item)); ok {
+				matches = From(list)
+			}
+			return resultSelector(item, matches), true, nil
+		}
+		return next, func() error { return CloseIterator(iter) }
+	})
+}
+
+// Returns the sequence with each item (from the "outer" sequence) passed to the result selector along with a sequence of the items
+// from the "inner" sequence that have an equal key (comparing keys with GenericEqual), which may be empty. The order of both
+// sequences is preserved. If any function is strongly typed, it will be called via reflection.
+func (s LINQ) GroupJoinR(inner Sequence, outerKey, innerKey, resultSelector T) LINQ {
+	return s.GroupJoin(inner, genericSelectorFunc(outerKey), genericSelectorFunc(innerKey), genericAggregatorFunc(resultSelector))
+}
+
+// Implements Join, LeftJoin, and FullOuterJoin. If leftOuter is true, outer items with no matches are returned. If rightOuter is
+// true, inner items with no matches are returned.
+func (s LINQ) join(inner Sequence, outerKey, innerKey Selector, resultSelector Aggregator, leftOuter, rightOuter bool) LINQ {
+	var lookup *joinLookup
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		iter := s.Iterator()
+		var item T                   // the current outer item
+		var matches []T              // the inner items matching the current outer item that haven't been returned yet
+		var matched *HashSet         // the keys of the inner items that have been matched, if rightOuter is true
+		outerDone, index := false, 0 // index is the next inner item to consider after the outer sequence is done
+		if rightOuter {
+			matched = MakeHashSet(0)
+		}
+		next := func() (T, bool, error) {
+			if lookup == nil { // on the first call to Next, group the inner sequence by key
+				var err error
+				if lookup, err = makeJoinLookup(inner, innerKey); err != nil {
+					return nil, false, err
+				}
+			}
+
+			for len(matches) == 0 && !outerDone { // find the next outer item with matches, or without them if leftOuter is true
+				if !iter.Next() {
+					if err := IteratorError(iter); err != nil {
+						return nil, false, err
+					}
+					outerDone = true
+					break
+				}
+				item = iter.Current()
+				key := outerKey(item)
+				if list, ok := lookup.groups.TryGet(key); ok {
+					matches = list.([]T)
+					if matched != nil {
+						matched.Add(key)
+					}
+				} else if leftOuter {
+					return resultSelector(item, nil), true, nil
+				}
+			}
+			if len(matches) != 0 {
+				match := matches[0]
+				matches = matches[1:]
+				return resultSelector(item, match), true, nil
+			}
+
+			for rightOuter && index < len(lookup.items) { // if rightOuter is true, return the inner items that weren't matched
+				index++
+				if !matched.Contains(lookup.keys[index-1]) {
+					return resultSelector(nil, lookup.items[index-1]), true, nil
+				}
+			}
+			return nil, false, nil
+		}
+		return next, func() error { return CloseIterator(iter) }
+	})
+}
+
+// A joinLookup holds the items from the inner sequence of a join, grouped by key.
+type joinLookup struct {
+	groups      *OrderedDictionary // maps keys to slices of inner items
+	items, keys []T                // the inner items and their keys, in order
+}
+
+// Creates a joinLookup from the items in a sequence, or returns the error that ended the iteration or from closing the iterator.
+func makeJoinLookup(inner Sequence, keySelector Selector) (*joinLookup, error) {
+	lookup := &joinLookup{groups: MakeOrderedDictionary(0)}
+	err := From(inner).TryForEach(func(item T) {
+		key := keySelector(item)
+		addToGroup(lookup.groups, key, item)
+		lookup.items, lookup.keys = append(lookup.items, item), append(lookup.keys, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lookup, nil
+}
@@ -55,7 +55,7 @@ var Empty = From(make([]T, 0, 0))
 // Converts an object into a LINQ that reads from the object. The object must be of a kind that can be converted to
 // a Sequence with ToSequence. If the value cannot be converted to a sequence, the function panics.
 func From(obj T) LINQ {
-	seq, err := ToSequence(obj)
+	seq, err := toLinqSequence(obj)
 	if err != nil {
 		panic(err)
 	}
@@ -65,7 +65,7 @@ func From(obj T) LINQ {
 // Attempts to convert an object into a LINQ that reads from the object. The object should be of a kind that can be
 // converted to a Sequence with ToSequence.
 func TryFrom(obj T) (LINQ, error) {
-	seq, err := ToSequence(obj)
+	seq, err := toLinqSequence(obj)
 	return LINQ{seq}, err
 }
 
@@ -76,7 +76,9 @@ func FromItems(items ...T) LINQ {
 
 // Indicates whether the given predicate is true for all items in the sequence. If the sequence is empty, the result is true.
 func (s LINQ) All(pred Predicate) bool {
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		if !pred(i.Current()) {
 			return false
 		}
@@ -92,7 +94,9 @@ func (s LINQ) AllR(pred T) bool {
 
 // Indicates whether the sequence has any items (i.e. is not empty).
 func (s LINQ) Any() bool {
-	return s.Iterator().Next()
+	i := s.Iterator()
+	defer finishIterators(i)
+	return i.Next()
 }
 
 // Indicates whether the sequence has any items matching the given predicate. If the sequence is empty, the result is false.
@@ -111,32 +115,37 @@ func (s LINQ) AnyR(pred T) bool {
 // more than once.
 func (s LINQ) Cache() LINQ {
 	var items []T
-	return FromSequenceFunction(func() IteratorFunc {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		index := 0
-		return func() (T, bool) {
+		return func() (T, bool, error) {
 			if items == nil {
-				items = ToSlice(s.Sequence)
+				var err error
+				if items, err = TryToSlice(s.Sequence); err != nil {
+					return nil, false, err
+				}
 			}
 
 			if index < len(items) {
 				item := items[index]
 				index++
-				return item, true
+				return item, true, nil
 			}
-			return nil, false
-		}
+			return nil, false, nil
+		}, nil
 	})
 }
 
 // Indicates whether the sequence contains the given item. If the sequence is a Collection, its Contains(T) method will be called.
 // Otherwise, the sequence will be iterated and a generic comparison made for each item. If you want to use a custom comparison,
-// call AnyP(predicate) or AnyR(predicate).
+// call ContainsP(item, cmp), AnyP(predicate), or AnyR(predicate).
 func (s LINQ) Contains(item T) bool {
 	if col, ok := s.Sequence.(Collection); ok {
 		return col.Contains(item)
 	}
 	cmp := MakeContainsComparer(item)
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		if cmp(i.Current()) {
 			return true
 		}
@@ -144,14 +153,31 @@ func (s LINQ) Contains(item T) bool {
 	return false
 }
 
+// Indicates whether the sequence contains an item equal to the given item according to the given equality function. For example,
+// passing DeepEqual compares slices, maps, and pointers by their contents. If the function is nil, Contains(item) is called.
+func (s LINQ) ContainsP(item T, cmp EqualFunc) bool {
+	if cmp == nil {
+		return s.Contains(item)
+	}
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
+		if cmp(item, i.Current()) {
+			return true
+		}
+	}
+	return false
+}
+
 // Counts the number of items in the sequence. If the sequence is a Collection, its Count() method will be called. Otherwise, the
 // sequence will be iterated and the items counted.
 func (s LINQ) Count() int {
 	if col, ok := s.Sequence.(Collection); ok {
 		return col.Count()
 	}
-	count := 0
-	for i := s.Iterator(); i.Next(); count++ {
+	count, i := 0, s.Iterator()
+	defer finishIterators(i)
+	for ; i.Next(); count++ {
 	}
 	return count
 }
@@ -167,9 +193,24 @@ func (s LINQ) CountR(pred T) int {
 	return s.WhereR(pred).Count()
 }
 
-// Calls an action for each item in the sequence.
+// Counts the number of items in the sequence, or returns the error that ended the iteration or from closing the iterator. If the
+// sequence is a Collection, its Count() method will be called.
+func (s LINQ) TryCount() (int, error) {
+	if col, ok := s.Sequence.(Collection); ok {
+		return col.Count(), nil
+	}
+	count := 0
+	if err := s.TryForEach(func(T) { count++ }); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Calls an action for each item in the sequence. The iterator is closed afterward, even if the action panics.
 func (s LINQ) ForEach(action Action) LINQ {
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		action(i.Current())
 	}
 	return s
@@ -181,9 +222,11 @@ func (s LINQ) ForEachR(action T) LINQ {
 	return s.ForEach(genericActionFunc(action))
 }
 
-// Calls an action with the index and value of each item in the sequence.
+// Calls an action with the index and value of each item in the sequence. The iterator is closed afterward, even if the action panics.
 func (s LINQ) ForEachIV(action func(int, T)) LINQ {
-	for i, index := s.Iterator(), 0; i.Next(); index++ {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for index := 0; i.Next(); index++ {
 		action(index, i.Current())
 	}
 	return s
@@ -200,61 +243,98 @@ func (s LINQ) ForEachKVR(action T) LINQ {
 	return s.ForEach(KVActionR(action))
 }
 
+// Calls an action for each item in the sequence and returns the error that ended the iteration or from closing the iterator. The
+// iterator is closed afterward, even if the action panics.
+func (s LINQ) TryForEach(action Action) error {
+	i := s.Iterator()
+	defer CloseIterator(i)
+	for i.Next() {
+		action(i.Current())
+	}
+	return FinishIterator(i)
+}
+
 // Transforms the sequence into a sequence of pairs whose keys are the result of the keySelector and whose values are sequences of
-// items having the same key. The order of items within each group is preserved, but the order of the groups is not.
+// items having the same key. Keys are compared with GenericEqual. The groups are returned in the order in which their keys first
+// appear, and the order of items within each group is preserved. (Use ToLookup to look up groups by key.)
 func (s LINQ) GroupBy(keySelector Selector) LINQ {
 	return s.GroupByKV(keySelector, nil)
 }
 
 // Transforms the sequence into a sequence of pairs whose keys are the result of the keySelector and whose values are sequences of
-// items having the same key. The order of items within each group is preserved, but the order of the groups is not.
-// If the selector is strongly typed, it will be called via reflection.
+// items having the same key. The groups are returned in the order in which their keys first appear, and the order of items within
+// each group is preserved. If the selector is strongly typed, it will be called via reflection.
 func (s LINQ) GroupByR(keySelector T) LINQ {
 	return s.GroupByKVR(keySelector, nil)
 }
 
 // Transforms the sequence into a sequence of pairs whose keys are the result of the keySelector and whose values are sequences of
 // values returned from the valueSelector for each item having the same key. (The valueSelector is taken to be an identity function
-// if nil.) The order of items within each group is preserved, but the order of the groups is not.
+// if nil.) The groups are returned in the order in which their keys first appear, and the order of items within each group is
+// preserved.
 func (s LINQ) GroupByKV(keySelector, valueSelector Selector) LINQ {
-	m := make(map[T][]T)
-	for i := s.Iterator(); i.Next(); {
+	return From(s.ToLookupKV(keySelector, valueSelector))
+}
+
+// Transforms the sequence into a sequence of pairs whose keys are the result of the keySelector and whose values are sequences of
+// values returned from the valueSelector for each item having the same key. (The valueSelector is taken to be an identity function
+// if nil.) The groups are returned in the order in which their keys first appear, and the order of items within each group is
+// preserved. If either selector is strongly typed, it will be called via reflection.
+func (s LINQ) GroupByKVR(keySelector, valueSelector T) LINQ {
+	return s.GroupByKV(genericSelectorFunc(keySelector), genericSelectorFunc(valueSelector))
+}
+
+// Groups the items in the sequence by the key returned from the keySelector (comparing keys with GenericEqual) and returns a
+// Lookup that maps each key to a sequence of the items having that key. The order of items within each group is preserved.
+func (s LINQ) ToLookup(keySelector Selector) *Lookup {
+	return s.ToLookupKV(keySelector, nil)
+}
+
+// Groups the items in the sequence by the key returned from the keySelector (comparing keys with GenericEqual) and returns a
+// Lookup that maps each key to a sequence of the items having that key. If the selector is strongly typed, it will be called via
+// reflection.
+func (s LINQ) ToLookupR(keySelector T) *Lookup {
+	return s.ToLookupKVR(keySelector, nil)
+}
+
+// Groups the items in the sequence by the key returned from the keySelector (comparing keys with GenericEqual) and returns a
+// Lookup that maps each key to a sequence of the values returned from the valueSelector for the items having that key. (The
+// valueSelector is taken to be an identity function if nil.) The order of items within each group is preserved.
+func (s LINQ) ToLookupKV(keySelector, valueSelector Selector) *Lookup {
+	m := MakeOrderedDictionary(0) // use a Dictionary that compares keys with GenericEqual, so any key can be used
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		v := i.Current()
 		k := keySelector(v)
 		if valueSelector != nil {
 			v = valueSelector(v)
 		}
 
-		if list, ok := m[k]; ok {
-			m[k] = append(list, v)
-		} else {
-			m[k] = []T{v}
-		}
-	}
-
-	seqs := make(map[T]LINQ)
-	for k, v := range m {
-		seqs[k] = From(v)
+		addToGroup(m, k, v)
 	}
-	return From(seqs)
+	return makeLookup(m)
 }
 
-// Transforms the sequence into a sequence of pairs whose keys are the result of the keySelector and whose values are sequences of
-// values returned from the valueSelector for each item having the same key. (The valueSelector is taken to be an identity function
-// if nil.) The order of items within each group is preserved, but the order of the groups is not.
-// If either selector is strongly typed, it will be called via reflection.
-func (s LINQ) GroupByKVR(keySelector, valueSelector T) LINQ {
-	return s.GroupByKV(genericSelectorFunc(keySelector), genericSelectorFunc(valueSelector))
+// Groups the items in the sequence by the key returned from the keySelector (comparing keys with GenericEqual) and returns a
+// Lookup that maps each key to a sequence of the values returned from the valueSelector for the items having that key. (The
+// valueSelector is taken to be an identity function if nil.) If either selector is strongly typed, it will be called via
+// reflection.
+func (s LINQ) ToLookupKVR(keySelector, valueSelector T) *Lookup {
+	return s.ToLookupKV(genericSelectorFunc(keySelector), genericSelectorFunc(valueSelector))
 }
 
 // Returns the sequence in reverse order.
 func (s LINQ) Reverse() LINQ {
 	var items []T
-	return FromSequenceFunction(func() IteratorFunc {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		index := 0
-		return func() (T, bool) {
+		return func() (T, bool, error) {
 			if items == nil { // on the first call to Next, generate and reverse the items
-				items = ToSlice(s.Sequence)
+				var err error
+				if items, err = TryToSlice(s.Sequence); err != nil {
+					return nil, false, err
+				}
 				for i, e, mid := 0, len(items)-1, len(items)/2; i < mid; i++ { // reverse the array
 					items[i], items[e-i] = items[e-i], items[i]
 				}
@@ -263,17 +343,16 @@ func (s LINQ) Reverse() LINQ {
 			if index < len(items) {
 				item := items[index]
 				index++
-				return item, true
+				return item, true, nil
 			}
-			return nil, false
-		}
+			return nil, false, nil
+		}, nil
 	})
 }
 
 // Returns the sequence with each item transformed by a selector function.
 func (s LINQ) Select(selector Selector) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
-		i := s.Iterator()
+	return s.pipe(func(i Iterator) IteratorFunc {
 		return func() (T, bool) {
 			if i.Next() {
 				return selector(i.Current()), true
@@ -303,13 +382,13 @@ func (s LINQ) SelectKVR(selector T) LINQ {
 // Transforms each item into a sequence using the selector - nils are considered empty sequences - and returns a new sequence that
 // is the concatenation of all the sequences.
 func (s LINQ) SelectMany(selector Selector) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		var outer, inner Iterator = s.Iterator(), nil
-		return func() (T, bool) {
+		next := func() (T, bool, error) {
 			for {
 				if inner == nil {
 					if !outer.Next() {
-						return nil, false
+						return nil, false, IteratorError(outer)
 					}
 					o := selector(outer.Current())
 					if o == nil {
@@ -318,11 +397,16 @@ func (s LINQ) SelectMany(selector Selector) LINQ {
 					inner = toSequenceOrDie(o).Iterator()
 				}
 				if inner.Next() {
-					return inner.Current(), true
+					return inner.Current(), true, nil
+				}
+				CloseIterator(inner)
+				if err := IteratorError(inner); err != nil {
+					return nil, false, err
 				}
 				inner = nil
 			}
 		}
+		return next, func() error { return closeIterators(outer, inner) }
 	})
 }
 
@@ -337,7 +421,8 @@ func (s LINQ) SequenceEqual(seq Sequence) bool {
 	return s.SequenceEqualP(seq, nil)
 }
 
-// Determines whether the sequence is equal to the given sequence, using the give equality function.
+// Determines whether the sequence is equal to the given sequence, using the given equality function (or GenericEqual if nil). For
+// example, passing DeepEqual compares slices, maps, and pointers by their contents.
 func (s LINQ) SequenceEqualP(seq Sequence, cmp EqualFunc) bool {
 	if c1, ok := s.Sequence.(Collection); ok {
 		if c2, ok := seq.(Collection); ok && c1.Count() != c2.Count() {
@@ -350,6 +435,7 @@ func (s LINQ) SequenceEqualP(seq Sequence, cmp EqualFunc) bool {
 	}
 
 	i1, i2 := s.Iterator(), seq.Iterator()
+	defer finishIterators(i1, i2)
 	for {
 		m1, m2 := i1.Next(), i2.Next()
 		if m1 != m2 {
@@ -362,7 +448,8 @@ func (s LINQ) SequenceEqualP(seq Sequence, cmp EqualFunc) bool {
 	}
 }
 
-// Determines whether the sequence is equal to the given sequence, using the give equality function.
+// Determines whether the sequence is equal to the given sequence, using the given equality function (or GenericEqual if nil). For
+// example, passing DeepEqual compares slices, maps, and pointers by their contents.
 // If the comparer is strongly typed, it will be called via reflection.
 func (s LINQ) SequenceEqualR(seq Sequence, cmp T) bool {
 	return s.SequenceEqualP(seq, genericEqualFunc(cmp))
@@ -378,6 +465,11 @@ func (s LINQ) ToSlice() []T {
 	return ToSlice(s.Sequence)
 }
 
+// Converts the sequence to a slice, or returns the error that ended the iteration or from closing the iterator.
+func (s LINQ) TryToSlice() ([]T, error) {
+	return TryToSlice(s.Sequence)
+}
+
 // Converts the sequence to a strongly-typed slice. The type of the first item will determine the element type of the slice.
 // If the sequence is empty, nil will be returned.
 func (s LINQ) ToSliceT() T {
@@ -386,8 +478,7 @@ func (s LINQ) ToSliceT() T {
 
 // Filters the sequence to remove items that do not match the given predicate.
 func (s LINQ) Where(pred Predicate) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
-		i := s.Iterator()
+	return s.pipe(func(i Iterator) IteratorFunc {
 		return func() (T, bool) {
 			for {
 				if !i.Next() {
@@ -453,6 +544,82 @@ func Repeat(item T, count int) LINQ {
 	})
 }
 
+// Closes the given iterators, ignoring nils, and returns the first error.
+func closeIterators(iters ...Iterator) error {
+	var err error
+	for _, i := range iters {
+		if i != nil {
+			if e := CloseIterator(i); err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Returns the first error that ended the iteration of one of the given iterators.
+func iteratorsError(iters ...Iterator) error {
+	for _, i := range iters {
+		if err := IteratorError(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Closes the given iterators, which a terminal operation is done with, and panics with the first error that ended an iteration.
+func finishIterators(iters ...Iterator) {
+	var err error
+	for _, i := range iters {
+		CloseIterator(i)
+		if e := IteratorError(i); err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Returns a sequence whose iterators get items from the IteratorFunc that the given function returns for an iterator over the
+// receiver. The receiver's iterator is closed when the returned iterator is closed or exhausted, and if the receiver's iteration
+// fails, the returned iterator fails with the same error.
+func (s LINQ) pipe(f func(Iterator) IteratorFunc) LINQ {
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		i := s.Iterator()
+		next := f(i)
+		tryNext := func() (T, bool, error) {
+			if item, ok := next(); ok {
+				return item, true, nil
+			}
+			return nil, false, IteratorError(i)
+		}
+		return tryNext, func() error { return CloseIterator(i) }
+	})
+}
+
+// Adds an item to the group with the given key in a dictionary that maps keys to slices of items.
+func addToGroup(groups *OrderedDictionary, key, item T) {
+	if list, ok := groups.TryGet(key); ok {
+		groups.Set(key, append(list.([]T), item))
+	} else {
+		groups.Set(key, []T{item})
+	}
+}
+
+// Converts an object into a Sequence like ToSequence, but unwraps LINQ objects. (Otherwise, since a LINQ has Count and Contains
+// methods, it would be treated as a Collection, and counting it would iterate the sequence an extra time.)
+func toLinqSequence(obj T) (Sequence, error) {
+	switch s := obj.(type) {
+	case LINQ:
+		return s.Sequence, nil
+	case OrderedLINQ:
+		return s.Sequence, nil
+	default:
+		return ToSequence(obj)
+	}
+}
+
 func toSequenceOrDie(obj T) Sequence {
 	seq, err := ToSequence(obj)
 	if err != nil {
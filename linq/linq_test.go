@@ -20,6 +20,7 @@ Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
 package linq
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -163,6 +164,17 @@ func TestLinqAggregate(t *testing.T) {
 	assertPanic(t, func() { FromItems(1.1, false).Sum() }, "cannot be added to float")
 	assertPanic(t, func() { FromItems(1+1i, false).Sum() }, "cannot be added to complex number")
 	assertPanic(t, func() { FromItems("hello", 1).Sum() }, "cannot be added to string")
+	// test named types
+	type age uint8
+	type name string
+	assertEqual(t, FromItems(age(20), age(30), age(1)).Sum(), age(51))
+	assertEqual(t, FromItems(age(20)).Sum(), age(20))
+	assertEqual(t, FromItems(age(200), age(100)).Sum(), age(44)) // sums of named types aren't widened
+	assertEqual(t, FromItems(age(20), 5.5).Sum(), 25.5)          // mixed types are added as their underlying types
+	assertEqual(t, FromItems(name("a"), name("b")).Sum(), name("ab"))
+	assertEqual(t, FromItems(name("a"), "b").Sum(), "ab")
+	assertEqual(t, FromItems(age(20), age(30), age(1)).Max(), age(30))
+	assertEqual(t, FromItems(name("b"), name("c"), name("a")).Min(), name("a"))
 	_, ok := Empty.TrySum()
 	assertFalse(t, ok, "Empty.TrySum")
 	assertPanic(t, func() { Empty.Sum() }, "empty")
@@ -369,6 +381,38 @@ func TestLinqBasics(t *testing.T) {
 		}
 	}
 
+	// test that groups are returned in the order their keys first appear
+	words := FromItems("pear", "fig", "apple", "kiwi", "plum", "date", "banana")
+	assertLinqEqual(t, words.GroupByR(func(w string) int { return len(w) }).Select(SelectPairKey), 4, 3, 5, 6)
+
+	// test lookups
+	lookup := words.ToLookupR(func(w string) int { return len(w) })
+	assertEqual(t, lookup.Count(), 4)
+	assertEqual(t, lookup.CountOf(4), 4)
+	assertEqual(t, lookup.CountOf(3), 1)
+	assertEqual(t, lookup.CountOf(7), 0)
+	assertLinqEqual(t, lookup.Get(4).(LINQ), "pear", "kiwi", "plum", "date")
+	assertLinqEqual(t, lookup.Get(7).(LINQ)) // missing keys give empty sequences
+	items, ok := lookup.TryGet(7)
+	assertTrue(t, !ok && items.(LINQ).Count() == 0, "TryGet found a missing key")
+	assertTrue(t, lookup.ContainsKey(5) && !lookup.ContainsKey("5"), "ContainsKey")
+	assertTrue(t, lookup.Contains(Pair{6, []string{"banana"}}), "Contains")
+	assertFalse(t, lookup.Contains(Pair{6, []string{"cherry"}}), "Contains")
+	assertFalse(t, lookup.Contains(Pair{6, nil}), "Contains")
+	assertLinqEqual(t, lookup.Keys(), 4, 3, 5, 6)
+	assertLinqEqual(t, From(lookup).Select(func(p T) T { return p.(Pair).Value.(LINQ).Count() }), 4, 1, 1, 1)
+	lookup = words.ToLookupKV(func(w T) T { return w.(string)[0] }, func(w T) T { return len(w.(string)) })
+	assertLinqEqual(t, lookup.Get(byte('p')).(LINQ), 4, 4)
+	assertLinqEqual(t, lookup.Get(byte('b')).(LINQ), 6)
+	assertLinqEqual(t, Range(5).ToLookupKVR(func(i int) bool { return i%2 == 0 }, strconv.Itoa).Get(true).(LINQ), "0", "2", "4")
+	lookup = &Lookup{} // the zero value is an empty lookup
+	assertEqual(t, lookup.Count(), 0)
+	assertEqual(t, lookup.CountOf(4), 0)
+	assertFalse(t, lookup.ContainsKey(4), "ContainsKey")
+	assertLinqEqual(t, lookup.Get(4).(LINQ))
+	assertLinqEqual(t, lookup.Keys())
+	assertLinqEqual(t, From(lookup))
+
 	s2 = FromItems(2, 3, 4).Prepend(7, 8, 9)
 	assertLinqEqual(t, s2, 7, 8, 9, 2, 3, 4)
 	assertLinqEqual(t, s2.Reverse(), 4, 3, 2, 9, 8, 7)
@@ -455,6 +499,96 @@ func TestLinqChannel(t *testing.T) {
 	assertPanic(t, func() { Range(10).SequenceEqual(cs) }, "sequence already iterated")
 }
 
+func TestLinqClose(t *testing.T) {
+	t.Parallel()
+
+	opened, closed := int32(0), int32(0)
+	src := FromClosableSequenceFunction(func() (IteratorFunc, func() error) { // an infinite sequence of integers
+		atomic.AddInt32(&opened, 1)
+		n := 0
+		return func() (T, bool) {
+			n++
+			return n, true
+		}, func() error { atomic.AddInt32(&closed, 1); return nil }
+	})
+	assertClosed := func(f func(), count int32) {
+		t.Helper()
+		atomic.StoreInt32(&opened, 0)
+		atomic.StoreInt32(&closed, 0)
+		f()
+		if o, c := atomic.LoadInt32(&opened), atomic.LoadInt32(&closed); o != count || c != count {
+			t.Fatalf("expected %d iterators to be opened and closed, but %d were opened and %d were closed", count, o, c)
+		}
+	}
+
+	// test that terminal operations close the iterator
+	assertClosed(func() { assertEqual(t, src.First(), 1) }, 1)
+	assertClosed(func() { assertTrue(t, src.Any(), "src.Any()") }, 1)
+	assertClosed(func() { assertFalse(t, src.All(func(i T) bool { return i.(int) < 5 }), "src.All(<5)") }, 1)
+	assertClosed(func() { assertTrue(t, src.Contains(3), "src.Contains(3)") }, 1)
+	assertClosed(func() { assertTrue(t, src.ContainsP(3, GenericEqual), "src.ContainsP(3)") }, 1)
+	assertClosed(func() { assertFalse(t, src.SequenceEqual(Range(5)), "src == Range(5)") }, 1)
+	assertClosed(func() { _, err := src.TrySingle(); assertTrue(t, IsTooManyItemsError(err), "src.TrySingle()") }, 1)
+	assertPanic(t, func() {
+		assertClosed(func() {
+			defer func() { assertEqual(t, atomic.LoadInt32(&closed), int32(1)); panic("done") }()
+			src.ForEach(func(i T) {
+				if i.(int) == 3 {
+					panic("stop")
+				}
+			})
+		}, 1)
+	}, "done")
+
+	// test that operators close the iterators they abandon
+	assertClosed(func() { assertLinqEqual(t, src.Take(3), 1, 2, 3) }, 2)
+	assertClosed(func() { assertLinqEqual(t, src.TakeWhile(func(i T) bool { return i.(int) < 3 }), 1, 2) }, 2)
+	assertClosed(func() {
+		assertEqual(t, src.Where(func(i T) bool { return i.(int) > 2 }).Select(genericAddOne).First(), 4)
+	}, 1)
+	assertClosed(func() { assertEqual(t, src.Skip(2).SkipWhile(func(i T) bool { return i.(int) < 5 }).First(), 5) }, 1)
+	assertClosed(func() { assertEqual(t, src.Distinct().Except(Range(3)).Intersect(Range(10)).First(), 3) }, 1)
+	assertClosed(func() { assertEqual(t, Range(2).Concat(src, src).Skip(3).First(), 2) }, 1)
+	assertClosed(func() { assertEqual(t, src.SelectMany(func(T) T { return src }).First(), 1) }, 2)
+	assertClosed(func() {
+		assertLinqEqual(t, src.Zip(Range(2), func(a, b T) T { return Pair{a, b} }), Pair{1, 0}, Pair{2, 1})
+	}, 2)
+	assertClosed(func() { assertEqual(t, Zip(func(items []T) T { return items[0] }, src, src).Skip(1).First(), 2) }, 2)
+	assertClosed(func() {
+		assertEqual(t, src.Merge(Range(3), nil, nil, func(a, b T) (T, bool) { return a, true }).Count(), 2)
+	}, 1)
+
+	// test that closing an iterator closes its sources
+	assertClosed(func() {
+		i := src.Select(genericAddOne).Where(func(T) bool { return true }).Iterator()
+		assertTrue(t, i.Next(), "i.Next()")
+		assertEqual(t, atomic.LoadInt32(&closed), int32(0))
+		assertEqual(t, CloseIterator(i), nil)
+		assertFalse(t, i.Next(), "i.Next() after Close")
+	}, 1)
+
+	// test that ParallelSelect stops reading from the source and waits for its goroutines when closed
+	assertClosed(func() {
+		running := int32(0)
+		i := src.ParallelSelect(4, func(v T) T {
+			atomic.AddInt32(&running, 1)
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return v
+		}).Iterator()
+		for n := 0; n < 10; n++ {
+			assertTrue(t, i.Next(), "i.Next()")
+		}
+		CloseIterator(i)
+		assertEqual(t, atomic.LoadInt32(&running), int32(0))
+		assertFalse(t, i.Next(), "i.Next() after Close")
+	}, 1)
+	assertClosed(func() { assertEqual(t, src.ParallelSelect(4, genericAddOne).Take(20).Count(), 20) }, 1)
+	assertClosed(func() {
+		assertSlicesEqual(t, src.ParallelSelectOrdered(4, 0, genericAddOne).Take(5).ToSlice(), 2, 3, 4, 5, 6)
+	}, 1)
+}
+
 func TestLinqContains(t *testing.T) {
 	t.Parallel()
 
@@ -497,6 +631,162 @@ func TestLinqContains(t *testing.T) {
 
 	assertTrue(t, MakeContainsComparer(nil)(p), "nil c= *int(0)")
 	assertFalse(t, MakeContainsComparer(p)(nil), "*int(0) c= p")
+
+	// test deep equality
+	s = From([][]int{{1, 2}, {3}})
+	assertFalse(t, s.Contains([]int{1, 2}), "Contains([1,2])")
+	assertTrue(t, s.ContainsP([]int{1, 2}, DeepEqual), "ContainsP([1,2], deep)")
+	assertFalse(t, s.ContainsP([]int{1}, DeepEqual), "ContainsP([1], deep)")
+	assertTrue(t, s.ContainsP([]int{3}, func(a, b T) bool { return a.([]int)[0] == b.([]int)[0] }), "ContainsP([3], custom)")
+	assertFalse(t, s.SequenceEqual(From([][]int{{1, 2}, {3}})), "SequenceEqual(copy)")
+	assertTrue(t, s.SequenceEqualP(From([][]int{{1, 2}, {3}}), DeepEqual), "SequenceEqualP(copy, deep)")
+	assertEqual(t, s.Concat(From([][]int{{3}, {1, 2}, {2, 1}})).DistinctP(DeepEqual, DeepHash).Count(), 3)
+	assertEqual(t, FromItems(Pair{1, []int{1}}, Pair{1, []int{1}}, Pair{2, []int{1}}).DistinctP(DeepEqual, nil).Count(), 2)
+	assertLinqEqual(t, Range(5).Concat(Range(5)).DistinctP(nil, nil), 0, 1, 2, 3, 4)
+}
+
+func TestLinqErrors(t *testing.T) {
+	t.Parallel()
+
+	failure := fmt.Errorf("read failed")
+	failing := FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) { // yields 1, 2, and 3, and then fails
+		n := 0
+		next := func() (T, bool, error) {
+			if n < 3 {
+				n++
+				return n, true, nil
+			}
+			return nil, false, failure
+		}
+		return next, nil
+	})
+	assertFails := func(s Sequence) {
+		t.Helper()
+		items, err := From(s).TryToSlice()
+		if err != failure {
+			t.Fatalf("expected the iteration to fail, but got %v and error %v", items, err)
+		}
+	}
+	keep := func(v T) (T, bool) { return v, true }
+	add := func(a, b T) T { return a.(int) + b.(int) }
+
+	// test that operators pass along the errors from their sources
+	assertFails(failing)
+	assertFails(failing.Select(genericAddOne).Where(func(T) bool { return true }))
+	assertFails(failing.Skip(1).SkipWhile(func(i T) bool { return i.(int) < 3 }).Take(10).TakeWhile(func(T) bool { return true }))
+	assertFails(failing.Distinct().Except(Range(2)).Intersect(Range(10)))
+	assertFails(Range(2).Except(failing))
+	assertFails(Range(2).Intersect(failing))
+	assertFails(Range(2).Concat(failing, Range(2)))
+	assertFails(failing.SelectMany(func(T) T { return Range(2) }))
+	assertFails(Range(2).SelectMany(func(T) T { return failing }))
+	assertFails(failing.Zip(Range(10), add))
+	assertFails(Zip(func(items []T) T { return items[0] }, Range(10), failing))
+	assertFails(failing.Merge(Range(10), keep, keep, func(a, b T) (T, bool) { return a, true }))
+	assertFails(Range(10).Merge(failing, keep, keep, func(a, b T) (T, bool) { return a, true }))
+	assertFails(failing.Order())
+	assertFails(failing.OrderByDescending(genericAddOne))
+	assertFails(failing.Reverse())
+	assertFails(failing.Join(Range(4), genericAddOne, genericAddOne, add))
+	assertFails(Range(4).Join(failing, genericAddOne, genericAddOne, add))
+	assertFails(failing.FullOuterJoin(Range(4), genericAddOne, genericAddOne, func(a, b T) T { return a }))
+	assertFails(failing.GroupJoin(Range(4), genericAddOne, genericAddOne, func(a, b T) T { return a }))
+	assertFails(Range(4).GroupJoin(failing, genericAddOne, genericAddOne, func(a, b T) T { return a }))
+	assertFails(failing.Cache())
+	assertFails(failing.ParallelSelect(4, genericAddOne))
+	assertFails(failing.ParallelSelectOrdered(4, 0, genericAddOne))
+	assertFails(failing.ParallelWhere(4, func(T) bool { return true }))
+	assertFails(failing.ParallelOrder(4))
+	assertFails(failing.ParallelOrderByDescending(4, genericAddOne))
+	assertFails(failing.ParallelWhereOrdered(4, 0, func(T) bool { return true }))
+	assertFails(failing.ParallelSelectMany(4, func(T) T { return Range(2) }))
+	assertFails(Range(2).ParallelSelectMany(4, func(T) T { return failing }))
+	assertFails(Range(2).ParallelSelectManyOrdered(4, 0, func(T) T { return failing }))
+	assertLinqEqual(t, Range(1).ParallelSelectManyOrdered(4, 0, func(T) T { return failing }).Take(3), 1, 2, 3)
+	assertLinqEqual(t, failing.Take(3), 1, 2, 3) // stopping before the failure isn't an error
+	assertEqual(t, failing.First(), 1)
+
+	// test the error-returning terminal operations
+	n, err := failing.TryCount()
+	assertTrue(t, n == 0 && err == failure, "TryCount fails")
+	sum := 0
+	assertEqual(t, failing.TryForEach(func(i T) { sum += i.(int) }), failure)
+	assertEqual(t, sum, 6)
+	v, err := failing.TryAggregateFrom(0, add)
+	assertTrue(t, v == nil && err == failure, "TryAggregateFrom fails")
+	v, err = failing.TryAggregateOrDefault(0, add)
+	assertTrue(t, v == nil && err == failure, "TryAggregateOrDefault fails")
+	n, err = Range(4).Where(func(T) bool { return true }).TryCount()
+	assertTrue(t, n == 4 && err == nil, "TryCount succeeds")
+	v, err = Range(4).TryAggregateFrom(10, add)
+	assertTrue(t, v == 16 && err == nil, "TryAggregateFrom succeeds")
+	v, err = Range(4).TryAggregateOrDefault(-1, add)
+	assertTrue(t, v == 6 && err == nil, "TryAggregateOrDefault succeeds")
+	v, err = Empty.TryAggregateOrDefault(-1, add)
+	assertTrue(t, v == -1 && err == nil, "TryAggregateOrDefault is empty")
+
+	// test that the error-returning terminal operations report errors from closing the iterator
+	closeFails := FromClosableSequenceFunction(func() (IteratorFunc, func() error) {
+		return func() (T, bool) { return nil, false }, func() error { return fmt.Errorf("close failed") }
+	})
+	_, err = closeFails.TryCount()
+	assertEqual(t, fmt.Sprint(err), "close failed")
+	assertEqual(t, closeFails.Count(), 0)
+
+	// test that other terminal operations panic with the error
+	assertPanic(t, func() { failing.Count() }, "read failed")
+	assertPanic(t, func() { failing.ToSlice() }, "read failed")
+	assertPanic(t, func() { failing.Sum() }, "read failed")
+	assertPanic(t, func() { failing.Last() }, "read failed")
+	assertPanic(t, func() { failing.Contains(4) }, "read failed")
+	assertPanic(t, func() { failing.ForEach(func(T) {}) }, "read failed")
+	assertPanic(t, func() { failing.SequenceEqual(failing) }, "read failed")
+	assertPanic(t, func() { failing.ToSliceT() }, "read failed")
+}
+
+func TestLinqJoin(t *testing.T) {
+	t.Parallel()
+
+	type customer struct {
+		ID   int
+		Name string
+	}
+	type order struct {
+		CustomerID T
+		Item       string
+	}
+	customers := FromItems(customer{1, "Ann"}, customer{2, "Bob"}, customer{3, "Cat"})
+	orders := FromItems(order{2, "pen"}, order{1, "ink"}, order{4, "cup"}, order{2, "pad"}, order{1, "box"})
+	customerID, orderID := func(c customer) int { return c.ID }, func(o order) T { return o.CustomerID }
+	describe := func(c customer, o order) string { return c.Name + ":" + o.Item }
+
+	assertLinqEqual(t, customers.JoinR(orders, customerID, orderID, describe), "Ann:ink", "Ann:box", "Bob:pen", "Bob:pad")
+	assertLinqEqual(t, customers.LeftJoinR(orders, customerID, orderID, describe), "Ann:ink", "Ann:box", "Bob:pen", "Bob:pad", "Cat:")
+	assertLinqEqual(t, customers.FullOuterJoinR(orders, customerID, orderID, describe),
+		"Ann:ink", "Ann:box", "Bob:pen", "Bob:pad", "Cat:", ":cup")
+	assertLinqEqual(t, customers.GroupJoinR(orders, customerID, orderID, func(c customer, os LINQ) T { return Pair{c.Name, os.Count()} }),
+		Pair{"Ann", 2}, Pair{"Bob", 2}, Pair{"Cat", 0})
+
+	// test that keys are compared with GenericEqual, which compares arrays structurally
+	assertLinqEqual(t, FromItems("ab", "cd").JoinR(FromItems("ba", "dc", "ab"), func(s string) T { return [2]byte{s[0], s[1]} },
+		func(s string) T { return [2]byte{s[1], s[0]} }, func(a, b string) T { return a + b }), "abba", "cddc")
+
+	// test the weakly typed versions, which pass nil for missing items
+	pair := func(a, b T) T { return Pair{a, b} }
+	assertLinqEqual(t, Range(4).Join(FromItems(6, 2, 5, 0), genericAddOne, func(i T) T { return i.(int) / 2 }, pair),
+		Pair{0, 2}, Pair{1, 5}, Pair{2, 6})
+	assertLinqEqual(t, Range(3).LeftJoin(FromItems("b", "c"), func(i T) T { return i }, func(s T) T { return len(s.(string)) }, pair),
+		Pair{0, nil}, Pair{1, "b"}, Pair{1, "c"}, Pair{2, nil})
+	assertLinqEqual(t, Range(2).FullOuterJoin(FromItems(1, 5, 1, 7), func(i T) T { return i }, func(i T) T { return i }, pair),
+		Pair{0, nil}, Pair{1, 1}, Pair{1, 1}, Pair{nil, 5}, Pair{nil, 7})
+	assertLinqEqual(t, Empty.FullOuterJoin(Range(2), genericAddOne, genericAddOne, pair), Pair{nil, 0}, Pair{nil, 1})
+	assertLinqEqual(t, Range(2).Join(Empty, genericAddOne, genericAddOne, pair))
+	groups := Range(3).GroupJoin(FromItems(0, 2, 4, 6), func(i T) T { return i }, func(i T) T { return i.(int) % 3 },
+		func(i, items T) T { return Pair{i, items.(LINQ).ToSlice()} }).ToSlice()
+	assertEqual(t, len(groups), 3)
+	assertSlicesEqual(t, groups[0].(Pair).Value.([]T), 0, 6)
+	assertSlicesEqual(t, groups[1].(Pair).Value.([]T), 4)
+	assertSlicesEqual(t, groups[2].(Pair).Value.([]T), 2)
 }
 
 func TestLinqMaps(t *testing.T) {
@@ -552,6 +842,19 @@ func TestLinqMaps(t *testing.T) {
 	m2 = Range(3).SelectR(func(i int) Pair { return Pair{kf(i), vf(i)} }).PairsToMap()
 	assertMapEqual(t, m2, 0, "0", 2, "0", 4, "1")
 	assertEqual(t, Empty.ToMapT(nil, nil), nil) // ToMapT on an empty sequence returns nil
+
+	// test producing insertion-ordered maps
+	words := FromItems("pear", "apple", "plum", "fig", "peach")
+	first := func(s string) string { return s[:1] }
+	assertLinqEqual(t, From(words.ToOrderedMapKR(first)), Pair{"p", "peach"}, Pair{"a", "apple"}, Pair{"f", "fig"})
+	assertLinqEqual(t, From(words.ToOrderedMapK(func(s T) T { return first(s.(string)) })), Pair{"p", "peach"}, Pair{"a", "apple"}, Pair{"f", "fig"})
+	assertLinqEqual(t, From(Range(3).ToOrderedMapVR(mul)), Pair{0, 0}, Pair{1, 2}, Pair{2, 4})
+	assertLinqEqual(t, From(Range(3).ToOrderedMapV(mulg)), Pair{0, 0}, Pair{1, 2}, Pair{2, 4})
+	assertLinqEqual(t, From(FromItems(2, 0, 1).ToOrderedMapR(mul, strconv.Itoa)), Pair{4, "2"}, Pair{0, "0"}, Pair{2, "1"})
+	assertLinqEqual(t, From(From(m).OrderBy(SelectPairKey).PairsToOrderedMap()), Pair{0, "0"}, Pair{2, "0"}, Pair{4, "1"})
+	od := MakeOrderedDictionary(0)
+	assertEqual(t, Range(2).AddToMapK(od, mulg), od)
+	assertLinqEqual(t, From(od), Pair{0, 0}, Pair{2, 1})
 }
 
 func TestLinqMerge(t *testing.T) {
@@ -617,6 +920,69 @@ func TestLinqOrder(t *testing.T) {
 	assertLinqEqual(t, Range(3).OrderByPR(func(i int) T { return -i }, func(a, b int) bool { return a < b }), 2, 1, 0)
 	assertLinqEqual(t, Range(3).OrderByDescendingP(func(i T) T { return -i.(int) }, func(a, b T) bool { return a.(int) < b.(int) }), 0, 1, 2)
 	assertLinqEqual(t, Range(3).OrderByDescendingPR(func(i int) T { return -i }, func(a, b int) bool { return a < b }), 0, 1, 2)
+
+	// test structural comparison of composite keys
+	type sale struct {
+		Region string
+		Day    int
+		Amount float64
+	}
+	type key struct {
+		Region string
+		Day    int `linq:"desc"`
+	}
+	sales := FromItems(sale{"west", 1, 5}, sale{"east", 1, 3}, sale{"west", 2, 4}, sale{"east", 2, 1})
+	assertLinqEqual(t, sales.OrderBy(func(s T) T { return key{s.(sale).Region, s.(sale).Day} }),
+		sale{"east", 2, 1}, sale{"east", 1, 3}, sale{"west", 2, 4}, sale{"west", 1, 5})
+	assertLinqEqual(t, sales.OrderBy(func(s T) T { return [2]T{s.(sale).Day, s.(sale).Amount} }),
+		sale{"east", 1, 3}, sale{"west", 1, 5}, sale{"east", 2, 1}, sale{"west", 2, 4})
+
+	// test a type that implements Comparable
+	assertLinqEqual(t, FromItems(caseless("b"), caseless("C"), caseless("a")).Order(), caseless("a"), caseless("b"), caseless("C"))
+	assertEqual(t, FromItems(caseless("b"), caseless("C"), caseless("a")).Max(), caseless("C"))
+
+	// test that the sorts are stable, using enough items that an unstable sort would likely reorder them
+	byTens := func(i T) T { return i.(int) / 10 }
+	tensCmp := func(a, b T) bool { return a.(int)/10 < b.(int)/10 }
+	scrambled := Range(1000).Select(func(i T) T { return i.(int) * 7919 % 1000 }).Cache()
+	descending := Range(100).Reverse().SelectMany(func(i T) T { return Range(10).Select(func(j T) T { return i.(int)*10 + j.(int) }) })
+	assertLinqEqual(t, Range(1000).OrderBy(byTens), Range(1000).ToSlice()...)
+	assertLinqEqual(t, Range(1000).OrderByDescending(byTens), descending.ToSlice()...)
+	assertLinqEqual(t, Range(1000).OrderP(tensCmp), Range(1000).ToSlice()...)
+	assertLinqEqual(t, Range(1000).OrderDescendingP(tensCmp), descending.ToSlice()...)
+	assertLinqEqual(t, scrambled.OrderBy(byTens).Where(func(i T) bool { return i.(int)/10 == 7 }),
+		scrambled.Where(func(i T) bool { return i.(int)/10 == 7 }).ToSlice()...)
+	assertLinqEqual(t, scrambled.OrderByDescending(byTens).Where(func(i T) bool { return i.(int)/10 == 7 }),
+		scrambled.Where(func(i T) bool { return i.(int)/10 == 7 }).ToSlice()...)
+	assertLinqEqual(t, seq.OrderDescendingP(func(a, b T) bool { return false }), seq.ToSlice()...)
+
+	// test ordering by multiple keys
+	type person struct {
+		First, Last string
+		Age         int
+	}
+	people := FromItems(person{"Jo", "Smith", 40}, person{"Al", "Jones", 30}, person{"Bo", "Smith", 25}, person{"Al", "Smith", 35},
+		person{"Cy", "Jones", 30})
+	last, first := func(p person) T { return p.Last }, func(p person) T { return p.First }
+	assertLinqEqual(t, people.OrderByR(last).ThenByR(first),
+		person{"Al", "Jones", 30}, person{"Cy", "Jones", 30}, person{"Al", "Smith", 35}, person{"Bo", "Smith", 25}, person{"Jo", "Smith", 40})
+	assertLinqEqual(t, people.OrderByR(last).ThenByDescendingR(first),
+		person{"Cy", "Jones", 30}, person{"Al", "Jones", 30}, person{"Jo", "Smith", 40}, person{"Bo", "Smith", 25}, person{"Al", "Smith", 35})
+	assertLinqEqual(t, people.OrderByDescending(func(p T) T { return p.(person).Age }).ThenBy(func(p T) T { return p.(person).First }),
+		person{"Jo", "Smith", 40}, person{"Al", "Smith", 35}, person{"Al", "Jones", 30}, person{"Cy", "Jones", 30}, person{"Bo", "Smith", 25})
+	byLast := people.OrderByR(last)
+	assertLinqEqual(t, byLast.ThenByPR(func(p person) int { return p.Age }, func(a, b int) bool { return a > b }).ThenByR(first),
+		person{"Al", "Jones", 30}, person{"Cy", "Jones", 30}, person{"Jo", "Smith", 40}, person{"Al", "Smith", 35}, person{"Bo", "Smith", 25})
+	assertLinqEqual(t, byLast.ThenByP(func(p T) T { return p.(person).Age }, nil).Select(func(p T) T { return p.(person).Age }),
+		30, 30, 25, 35, 40)
+	assertLinqEqual(t, byLast.Select(func(p T) T { return p.(person).First }), "Al", "Cy", "Jo", "Bo", "Al") // the base ordering is unchanged
+	assertLinqEqual(t, FromItems("b", "B", "a", "A").OrderR(cicmp).ThenByDescending(func(s T) T { return s }), "a", "A", "b", "B")
+
+	// test that each key is selected only once per item
+	selected := 0
+	counted := func(i T) T { selected++; return i.(int) / 10 }
+	assertEqual(t, Range(100).OrderByDescending(counted).ThenByDescending(counted).Count(), 100)
+	assertEqual(t, selected, 200)
 }
 
 func TestLinqParallelism(t *testing.T) {
@@ -653,6 +1019,115 @@ func TestLinqParallelism(t *testing.T) {
 	assertEqual(t, sum, int32(4950))
 	assertTrue(t, time.Now().Sub(startTime) < 300*time.Millisecond, "ParallelSelect(10) took too long")
 
+	/* test ParallelSelectOrdered */
+	jitter := func(i int) string { // take a varying amount of time so that items are transformed out of order
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+		return atoi(i)
+	}
+	assertLinqEqual(t, Range(50).ParallelSelectOrderedR(4, 0, jitter), Range(50).SelectR(atoi).ToSlice()...)
+	assertLinqEqual(t, Range(50).ParallelSelectOrderedR(8, 3, jitter), Range(50).SelectR(atoi).ToSlice()...)
+	assertLinqEqual(t, Range(10).ParallelSelectOrderedR(1, 0, atoi), Range(10).SelectR(atoi).ToSlice()...) // one core is special cased
+	assertPanic(t, func() { Range(10).ParallelSelectOrderedR(4, -1, atoi) }, "must be non-negative")
+	var returned []T
+	assertPanic(t, func() { // test that a panic is propagated after the items that precede it
+		Range(10).ParallelSelectOrderedR(4, 0, func(i int) int { pan(i); return i }).ForEach(func(i T) { returned = append(returned, i) })
+	}, "oh no")
+	assertSlicesEqual(t, returned, 0, 1, 2, 3, 4, 5)
+	reads := int32(0) // test that reading from the source stops once a selector panics
+	counted := Range(100).Select(func(i T) T { atomic.AddInt32(&reads, 1); return i })
+	assertPanic(t, func() { counted.ParallelSelectOrdered(4, 50, func(T) T { panic("oh no") }).Count() }, "oh no")
+	assertTrue(t, atomic.LoadInt32(&reads) <= 4, fmt.Sprintf("%d items were read", reads))
+
+	// test that a slow read from the source doesn't hold up items that have already been transformed
+	release := make(chan struct{})
+	slow := Range(10).Select(func(i T) T {
+		if i.(int) == 2 {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return i
+	})
+	startTime = time.Now()
+	it := slow.ParallelSelectOrdered(2, 0, genericAddOne).Iterator()
+	assertTrue(t, it.Next() && it.Next() && it.Current() == 2, "ParallelSelectOrdered returned the wrong items")
+	assertTrue(t, time.Now().Sub(startTime) < time.Second, "a slow read held up finished items")
+	close(release)
+	assertEqual(t, FinishIterator(it), nil)
+
+	// test that no more than 'window' items are read ahead of the consumer
+	pending, maxPending := int32(0), int32(0)
+	ordered := Range(100).Select(func(i T) T {
+		if n := atomic.AddInt32(&pending, 1); n > atomic.LoadInt32(&maxPending) {
+			atomic.StoreInt32(&maxPending, n) // the source is read by only one thread at a time
+		}
+		return i
+	}).ParallelSelectOrdered(8, 5, func(i T) T { time.Sleep(time.Millisecond); return i })
+	ordered.ForEach(func(T) { atomic.AddInt32(&pending, -1) })
+	// a worker may read another item after an item is returned but before the consumer has processed it, so allow one extra
+	assertTrue(t, atomic.LoadInt32(&maxPending) <= 6, fmt.Sprintf("%d items were pending", maxPending))
+
+	// test cancellation
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	items, err := Range(10).ParallelSelectOrderedCtx(cancelled, 4, 0, func(_ context.Context, i T) T { return i }).TryToSlice()
+	assertTrue(t, items == nil && err == context.Canceled, "ParallelSelectOrderedCtx didn't fail")
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	items, err = Range(10).ParallelSelectOrderedCtx(timeout, 4, 0, func(ctx context.Context, i T) T { <-ctx.Done(); return i }).TryToSlice()
+	assertTrue(t, items == nil && err == context.DeadlineExceeded, "ParallelSelectOrderedCtx didn't time out")
+
+	/* test ParallelWhere and ParallelSelectMany */
+	isEven := func(i int) bool { time.Sleep(time.Duration(i%3) * time.Millisecond); return i%2 == 0 }
+	evens := Range(25).Select(func(i T) T { return i.(int) * 2 }).ToSlice()
+	assertLinqEqual(t, Range(50).ParallelWhereR(8, isEven).Order(), evens...)
+	assertLinqEqual(t, Range(50).ParallelWhereR(1, isEven), evens...)
+	assertLinqEqual(t, Range(50).ParallelWhereOrderedR(8, 0, isEven), evens...)
+	assertLinqEqual(t, Range(50).ParallelWhereOrderedR(4, 3, isEven), evens...)
+	assertPanic(t, func() { Range(10).ParallelWhereR(-1, isEven) }, "must be non-negative")
+	assertPanic(t, func() { Range(10).ParallelWhereR(4, func(i int) bool { pan(i); return true }).Count() }, "oh no")
+	returned = nil
+	assertPanic(t, func() {
+		Range(10).ParallelWhereOrderedR(4, 0, func(i int) bool { pan(i); return true }).ForEach(func(i T) { returned = append(returned, i) })
+	}, "oh no")
+	assertSlicesEqual(t, returned, 0, 1, 2, 3, 4, 5)
+
+	expand := func(i int) T { // returns i copies of i, or nil for zero
+		if i == 0 {
+			return nil
+		}
+		return Repeat(i, i).Select(func(i T) T { time.Sleep(time.Duration(i.(int)%3) * time.Millisecond); return i })
+	}
+	expanded := Range(10).SelectManyR(expand).ToSlice()
+	assertLinqEqual(t, Range(10).ParallelSelectManyR(8, expand).Order(), expanded...)
+	assertLinqEqual(t, Range(10).ParallelSelectManyR(1, expand), expanded...)
+	assertLinqEqual(t, Range(10).ParallelSelectManyOrderedR(8, 0, expand), expanded...)
+	assertLinqEqual(t, Range(10).ParallelSelectManyOrderedR(3, 2, expand), expanded...)
+	assertPanic(t, func() { Range(10).ParallelSelectManyOrderedR(4, -1, expand) }, "must be non-negative")
+	assertPanic(t, func() { Range(10).ParallelSelectManyR(4, func(i int) T { pan(i); return nil }).Count() }, "oh no")
+
+	/* test ParallelOrder and ParallelOrderBy */
+	scrambled := Range(1000).Select(func(i T) T { return i.(int) * 7919 % 1000 }).ToSlice() // a permutation of 0-999
+	assertLinqEqual(t, From(scrambled).ParallelOrder(8), Range(1000).ToSlice()...)
+	assertLinqEqual(t, From(scrambled).ParallelOrder(1), Range(1000).ToSlice()...)
+	assertLinqEqual(t, From(scrambled).ParallelOrderDescending(0), Range(1000).Reverse().ToSlice()...)
+	assertLinqEqual(t, From(scrambled).ParallelOrderR(3, func(a, b int) bool { return a > b }), Range(1000).Reverse().ToSlice()...)
+	assertLinqEqual(t, FromItems(3, 1, 2).ParallelOrder(8), 1, 2, 3) // more threads than items
+	assertLinqEqual(t, Empty.ParallelOrder(4))
+	assertPanic(t, func() { Range(10).ParallelOrder(-1) }, "must be non-negative")
+	assertPanic(t, func() { Range(10).ParallelOrderByR(4, func(i int) int { pan(i); return i }).First() }, "oh no")
+
+	// test that the sorts are stable, even across partitions
+	byTens := func(i int) int { return i / 10 }
+	assertLinqEqual(t, From(scrambled).ParallelOrderByR(4, byTens).SelectR(byTens), Range(1000).SelectR(byTens).ToSlice()...)
+	assertLinqEqual(t, Range(1000).ParallelOrderByR(4, byTens), Range(1000).ToSlice()...)
+	assertLinqEqual(t, Range(100).ParallelOrderByR(16, func(int) int { return 0 }), Range(100).ToSlice()...) // ties across many partitions
+	assertLinqEqual(t, Range(1000).ParallelOrderByDescendingR(4, byTens),
+		Range(100).Reverse().SelectManyR(func(i int) T { return Range(10).Select(func(j T) T { return i*10 + j.(int) }) }).ToSlice()...)
+	assertLinqEqual(t, Range(1000).ParallelOrderDescendingR(4, func(a, b int) bool { return a/10 < b/10 }),
+		Range(1000).ParallelOrderByDescendingR(4, byTens).ToSlice()...)
+
 	/* test ParallelForEach */
 	// test with unlimited parallelism
 	sum, startTime = 0, time.Now()
@@ -683,6 +1158,65 @@ func TestLinqParallelism(t *testing.T) {
 
 	// test propagation of panics
 	assertPanic(t, func() { Range(10).ParallelForEachR(-1, pan) }, "oh no")
+
+	/* test cancellation */
+	// test that ParallelForEachCtx stops starting actions when the context is done and returns the context's error
+	for _, threads := range []int{-1, 1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		count := int32(0)
+		err := Range(1000).ParallelForEachCtx(ctx, threads, func(ctx context.Context, i T) {
+			if atomic.AddInt32(&count, 1) == 10 {
+				cancel()
+			}
+		})
+		assertEqual(t, err, context.Canceled)
+		if threads > 0 { // with unlimited threads, all the goroutines may be started before any of them cancels the context
+			assertTrue(t, atomic.LoadInt32(&count) < 100, "ParallelForEachCtx didn't stop")
+		}
+	}
+	assertEqual(t, Range(10).ParallelForEachCtx(context.Background(), 4, func(context.Context, T) {}), nil)
+
+	// test that selectors in progress can observe the context, and that ParallelSelectCtx fails with the context's error
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	items, err = Range(10).ParallelSelectCtx(ctx, 4, func(ctx context.Context, i T) T { <-ctx.Done(); return i }).TryToSlice()
+	assertTrue(t, items == nil && err == context.DeadlineExceeded, "ParallelSelectCtx didn't fail")
+	n, err := Range(10).ParallelSelectCtx(context.Background(), 4, func(_ context.Context, i T) T { return i }).TryCount()
+	assertTrue(t, n == 10 && err == nil, "ParallelSelectCtx failed")
+
+	/* test parallel aggregates */
+	appendItem := func(list, i T) T { return append(list.([]T), i) }
+	concat := func(a, b T) T { return append(a.([]T), b.([]T)...) }
+	newList := func() T { return []T(nil) }
+	assertLinqEqual(t, From(Range(100).ParallelAggregate(8, newList, appendItem, concat)).Order(), Range(100).ToSlice()...)
+	assertEqual(t, len(Range(100).ParallelAggregate(1, newList, appendItem, concat).([]T)), 100)
+	assertEqual(t, len(Empty.ParallelAggregate(4, newList, appendItem, concat).([]T)), 0) // an empty sequence returns a new seed
+	assertEqual(t, Range(1000).ParallelSum(8), int64(499500))
+	assertEqual(t, From([]float64{0.5, 1.5, 2}).ParallelSum(0), 4.0)
+	assertEqual(t, Range(1000).ParallelCountR(4, func(i int) bool { return i%3 == 0 }), 334)
+	assertEqual(t, Empty.ParallelCount(4, func(T) bool { return true }), 0)
+	assertEqual(t, From([]int{5, 3, 9, 1, 7, 2}).ParallelMax(3), 9)
+	assertEqual(t, From([]int{5, 3, 9, 1, 7, 2}).ParallelMin(3), 1)
+	assertEqual(t, From([]string{"bb", "a", "ccc"}).ParallelMaxR(2, func(a, b string) bool { return len(a) < len(b) }), "ccc")
+	assertEqual(t, From([]string{"bb", "a", "ccc"}).ParallelMinR(2, func(a, b string) bool { return len(a) < len(b) }), "a")
+	assertPanic(t, func() { Empty.ParallelSum(4) }, "empty")
+	assertPanic(t, func() { Empty.ParallelMax(4) }, "empty")
+	assertPanic(t, func() { Range(10).ParallelSum(-1) }, "must be non-negative")
+	assertPanic(t, func() { Range(10).ParallelCountR(4, func(i int) bool { pan(i); return true }) }, "oh no")
+
+	groups := Range(100).ParallelGroupByR(4, func(i int) int { return i % 3 }).OrderBy(SelectPairKey).ToSlice()
+	assertEqual(t, len(groups), 3)
+	for k, g := range groups {
+		p := g.(Pair)
+		assertEqual(t, p.Key, k)
+		assertLinqEqual(t, p.Value.(LINQ).Order(), Range(100).Where(func(i T) bool { return i.(int)%3 == k }).ToSlice()...)
+	}
+	assertEqual(t, Empty.ParallelGroupBy(4, genericAddOne).Count(), 0)
+	failing := FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		return func() (T, bool, error) { return nil, false, fmt.Errorf("read failed") }, nil
+	})
+	assertPanic(t, func() { failing.ParallelSum(4) }, "read failed")
+	assertPanic(t, func() { failing.ParallelGroupBy(4, genericAddOne) }, "read failed")
 }
 
 func TestLinqRegister(t *testing.T) {
@@ -695,6 +1229,18 @@ func TestLinqRegister(t *testing.T) {
 	RegisterSequenceCreator(reflect.TypeOf(bar{}), creator)
 
 	assertLinqEqual(t, From(bar{7, 3}), 7, 3)
+
+	type name []byte
+	RegisterComparer(reflect.TypeOf(name{}),
+		func(a, b T) bool { return string(a.(name)) == string(b.(name)) },
+		func(a, b T) bool { return string(a.(name)) < string(b.(name)) },
+		func(v T) uint64 { return GenericHash(string(v.(name))) })
+	names := FromItems(name("bob"), name("al"), name("bob"), name("cy"))
+	assertEqual(t, names.Distinct().Count(), 3)
+	assertTrue(t, names.Contains(name("cy")), "names.Contains(cy)")
+	assertEqual(t, string(names.Order().First().(name)), "al")
+	assertEqual(t, string(names.Max().(name)), "cy")
+	assertEqual(t, names.GroupBy(func(n T) T { return n }).Count(), 3)
 }
 
 func TestLinqSets(t *testing.T) {
@@ -708,6 +1254,50 @@ func TestLinqSets(t *testing.T) {
 	assertLinqEqual(t, s.Union(Range(5), Range2(10, 3), FromItems("hello", "goodbye")),
 		1, 2, 3, "hello", nil, p, 0, 4, 10, 11, 12, "goodbye")
 	assertEqual(t, s.Union(), s)
+
+	// test a type that implements Equatable
+	cs := FromItems(caseless("a"), caseless("B"), caseless("A"), caseless("b"), caseless("c"))
+	assertLinqEqual(t, cs.Distinct(), caseless("a"), caseless("B"), caseless("c"))
+	assertLinqEqual(t, cs.Except(FromItems(caseless("C"), caseless("b"))), caseless("a"), caseless("A"))
+	assertTrue(t, cs.Contains(caseless("C")), "cs.Contains(C)")
+	assertEqual(t, cs.GroupBy(func(i T) T { return i }).Count(), 3)
+
+	// test items that can't be used as go map keys
+	a, b, c := []int{1}, []int{1}, []int{1}
+	u := FromItems(a, Pair{1, a}, b, Pair{1, a}, foo{a, 1}, a, Pair{1, b}, foo{a, 1}, foo{b, 1})
+	assertLinqEqual(t, u.Distinct(), a, Pair{1, a}, b, foo{a, 1}, Pair{1, b}, foo{b, 1})
+	assertLinqEqual(t, u.Except(FromItems(a, Pair{1, b}, foo{a, 1})), Pair{1, a}, b, Pair{1, a}, foo{b, 1})
+	assertLinqEqual(t, u.Intersect(FromItems(foo{b, 1}, Pair{1, a}, a)), a, Pair{1, a}, foo{b, 1})
+	assertLinqEqual(t, u.Union(FromItems(b, c)).Skip(6), c)
+	groups := u.GroupBy(func(i T) T {
+		if p, ok := i.(Pair); ok {
+			return Pair{p.Value, "pair"}
+		}
+		return i
+	}).ToSliceT().([]Pair)
+	assertEqual(t, len(groups), 6)
+	for _, g := range groups {
+		if k, ok := g.Key.(Pair); ok && GenericEqual(k.Key, a) {
+			assertLinqEqual(t, g.Value.(LINQ), Pair{1, a}, Pair{1, a})
+		} else if GenericEqual(g.Key, foo{a, 1}) {
+			assertLinqEqual(t, g.Value.(LINQ), foo{a, 1}, foo{a, 1})
+		}
+	}
+}
+
+// a string that implements Equatable and Comparable, ignoring case
+type caseless string
+
+func (s caseless) CompareTo(o T) int {
+	return strings.Compare(strings.ToLower(string(s)), strings.ToLower(string(o.(caseless))))
+}
+
+func (s caseless) Equals(o T) bool {
+	return strings.EqualFold(string(s), string(o.(caseless)))
+}
+
+func (s caseless) Hash() uint64 {
+	return GenericHash(strings.ToLower(string(s)))
 }
 
 type foo struct {
@@ -761,7 +1351,7 @@ func areEqual(a, b T) bool {
 	} else if at == funcSeqType {
 		return areEqual(readField(a, "f"), readField(b, "f"))
 	} else {
-		return a == b // unknown struct. use default comparer
+		return GenericEqual(a, b) // unknown struct. use the generic comparer, which handles incomparable fields
 	}
 }
 
@@ -797,9 +1387,9 @@ func assertTrue(t *testing.T, value bool, message string) {
 	}
 }
 
-func assertLinqEqual(t *testing.T, seq LINQ, values ...T) {
+func assertLinqEqual(t *testing.T, seq Sequence, values ...T) {
 	assertSeqEqual(t, seq, values...)
-	assertTrue(t, seq.SequenceEqual(From(values)), "assertLinqEqual") // test double iteration of the sequence
+	assertTrue(t, From(seq).SequenceEqual(From(values)), "assertLinqEqual") // test double iteration of the sequence
 }
 
 func assertMapEqual(t *testing.T, m T, values ...T) {
@@ -880,3 +1470,7 @@ func readField(v T, name string) T {
 	f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
 	return f.Interface()
 }
+
+func genericAddOne(v T) T {
+	return v.(int) + 1
+}
@@ -0,0 +1,104 @@
+/*
+adammil.net/linq is a library that implements .NET-like LINQ queries for Go.
+
+http://www.adammil.net/
+Copyright (C) 2019 Adam Milazzo
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
+*/
+
+package linq
+
+import . "github.com/AdamMil/go/collections"
+
+// A Lookup is a ReadOnlyDictionary that maps keys to sequences (LINQ objects) of the items having those keys. It iterates through
+// the groups as Pairs in the order in which their keys first appeared in the source sequence. Unlike other dictionaries, it returns
+// an empty sequence for a missing key rather than panicking. Keys are compared with GenericEqual. The zero value is an empty lookup.
+type Lookup struct {
+	groups *OrderedDictionary // maps keys to slices of items
+}
+
+var _ ReadOnlyDictionary = &Lookup{}
+
+// Indicates whether the lookup contains the given Pair, whose value must be a sequence of the same items (compared with
+// GenericEqual) as the group with the given key.
+func (l *Lookup) Contains(item T) bool {
+	if p, ok := item.(Pair); ok {
+		if list, ok := l.dict().TryGet(p.Key); ok && p.Value != nil {
+			if seq, err := TryFrom(p.Value); err == nil {
+				return seq.SequenceEqual(From(list))
+			}
+		}
+	}
+	return false
+}
+
+// Indicates whether the lookup contains the given key.
+func (l *Lookup) ContainsKey(key T) bool {
+	return l.dict().ContainsKey(key)
+}
+
+// Returns the number of keys (i.e. groups) in the lookup.
+func (l *Lookup) Count() int {
+	return l.dict().Count()
+}
+
+// Returns the number of items having the given key, which is zero if the key does not exist.
+func (l *Lookup) CountOf(key T) int {
+	if list, ok := l.dict().TryGet(key); ok {
+		return len(list.([]T))
+	}
+	return 0
+}
+
+// Returns a sequence of the items having the given key, which is empty if the key does not exist.
+func (l *Lookup) Get(key T) T {
+	seq, _ := l.TryGet(key)
+	return seq
+}
+
+// Returns an iterator that returns the groups as Pairs whose values are sequences of items, in the order in which their keys first
+// appeared.
+func (l *Lookup) Iterator() Iterator {
+	return From(l.dict()).Select(func(i T) T {
+		p := i.(Pair)
+		return Pair{p.Key, From(p.Value)}
+	}).Iterator()
+}
+
+// Returns a sequence of the keys in the lookup, in the order in which they first appeared.
+func (l *Lookup) Keys() LINQ {
+	return From(l.dict().Keys())
+}
+
+// Attempts to get a sequence of the items having the given key. If the key does not exist, an empty sequence and false are
+// returned.
+func (l *Lookup) TryGet(key T) (T, bool) {
+	if list, ok := l.dict().TryGet(key); ok {
+		return From(list), true
+	}
+	return Empty, false
+}
+
+// Returns the dictionary that maps keys to slices of items, which is empty if the lookup is a zero value.
+func (l *Lookup) dict() *OrderedDictionary {
+	if l.groups == nil {
+		return &OrderedDictionary{}
+	}
+	return l.groups
+}
+
+// Creates a Lookup from a dictionary that maps keys to slices of items.
+func makeLookup(groups *OrderedDictionary) *Lookup {
+	return &Lookup{groups}
+}
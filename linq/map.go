@@ -32,16 +32,20 @@ func (s LINQ) AddPairsToMap(m T) T {
 }
 
 // Adds the sequence to a map, where the key and value for each item are extracted from the given selector functions. (Nil
-// functions are treated as identity functions.) The map is returned.
+// functions are treated as identity functions.) The map may also be a Dictionary, such as an OrderedDictionary. The map is returned.
 func (s LINQ) AddToMap(m T, getKey, getValue Selector) T {
 	if tm, ok := m.(map[T]T); ok { // if it's map[T]T use a specialized method
 		return addToMap(s.Sequence, tm, getKey, getValue)
+	} else if d, ok := m.(Dictionary); ok {
+		return addToDictionary(s.Sequence, d, getKey, getValue)
 	}
 	rm := reflect.ValueOf(m)
 	if rm.Kind() != reflect.Map {
 		panic("argument is not a map")
 	}
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		v := i.Current()
 		k := v
 		if getKey != nil {
@@ -135,13 +139,68 @@ func (s LINQ) ToMapVR(getValue T) map[T]T {
 	return s.ToMapR(nil, getValue)
 }
 
+// Converts the sequence to an OrderedDictionary, where the key and value for each item are extracted from the given selector
+// functions. (Nil functions are treated as identity functions.) Iterating the dictionary returns the keys in the order they were
+// first seen.
+func (s LINQ) ToOrderedMap(getKey, getValue Selector) *OrderedDictionary {
+	capacity := 0
+	if col, ok := s.Sequence.(Collection); ok {
+		capacity = col.Count()
+	}
+	d := MakeOrderedDictionary(capacity)
+	addToDictionary(s.Sequence, d, getKey, getValue)
+	return d
+}
+
+// Converts the sequence to an OrderedDictionary, where the key for each item is extracted from the given selector function and the
+// value is the item itself. Iterating the dictionary returns the keys in the order they were first seen.
+func (s LINQ) ToOrderedMapK(getKey Selector) *OrderedDictionary {
+	return s.ToOrderedMap(getKey, nil)
+}
+
+// Converts the sequence to an OrderedDictionary, where the key for each item is extracted from the given selector function and the
+// value is the item itself. Iterating the dictionary returns the keys in the order they were first seen. If the selector is strongly
+// typed, it will be called via reflection.
+func (s LINQ) ToOrderedMapKR(getKey T) *OrderedDictionary {
+	return s.ToOrderedMapR(getKey, nil)
+}
+
+// Converts the sequence to an OrderedDictionary, assuming the sequence is a sequence of Pairs. The key and value from each Pair will
+// become the key and value for each item added to the dictionary. Iterating the dictionary returns the keys in the order they were
+// first seen.
+func (s LINQ) PairsToOrderedMap() *OrderedDictionary {
+	return s.ToOrderedMap(SelectPairKey, SelectPairValue)
+}
+
+// Converts the sequence to an OrderedDictionary, where the key and value for each item are extracted from the given selector
+// functions. (Nil functions are treated as identity functions.) Iterating the dictionary returns the keys in the order they were
+// first seen. If either selector is strongly typed, it will be called via reflection.
+func (s LINQ) ToOrderedMapR(getKey T, getValue T) *OrderedDictionary {
+	return s.ToOrderedMap(genericSelectorFunc(getKey), genericSelectorFunc(getValue))
+}
+
+// Converts the sequence to an OrderedDictionary, where the value for each item is extracted from the given selector function and the
+// key is the item itself. Iterating the dictionary returns the keys in the order they were first seen.
+func (s LINQ) ToOrderedMapV(getValue Selector) *OrderedDictionary {
+	return s.ToOrderedMap(nil, getValue)
+}
+
+// Converts the sequence to an OrderedDictionary, where the value for each item is extracted from the given selector function and the
+// key is the item itself. Iterating the dictionary returns the keys in the order they were first seen. If the selector is strongly
+// typed, it will be called via reflection.
+func (s LINQ) ToOrderedMapVR(getValue T) *OrderedDictionary {
+	return s.ToOrderedMapR(nil, getValue)
+}
+
 // Converts the sequence to a strongly-typed map, where the key and value for each item are extracted from the given selector
 // functions. (Nil functions are treated as identity functions.) The key and value types from the first item will determine the type
 // of map. If the sequence is empty, nil will be returned.
 func (s LINQ) ToMapT(getKey, getValue Selector) T {
 	var m reflect.Value
 	initialized := false
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		v := i.Current()
 		k := v
 		if getKey != nil {
@@ -209,7 +268,9 @@ func (s LINQ) ToMapTVR(getValue T) T {
 }
 
 func addToMap(s Sequence, m map[T]T, getKey, getValue Selector) map[T]T {
-	for i := s.Iterator(); i.Next(); {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
 		v := i.Current()
 		k := v
 		if getKey != nil {
@@ -222,3 +283,20 @@ func addToMap(s Sequence, m map[T]T, getKey, getValue Selector) map[T]T {
 	}
 	return m
 }
+
+func addToDictionary(s Sequence, d Dictionary, getKey, getValue Selector) Dictionary {
+	i := s.Iterator()
+	defer finishIterators(i)
+	for i.Next() {
+		v := i.Current()
+		k := v
+		if getKey != nil {
+			k = getKey(k)
+		}
+		if getValue != nil {
+			v = getValue(v)
+		}
+		d.Set(k, v)
+	}
+	return d
+}
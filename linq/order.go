@@ -26,194 +26,281 @@ import (
 )
 
 // Returns the sequence ordered using the default comparison function (which can compare all numerics against each other,
-// booleans against each other, strings against each other, and nils against all types). Order among equal items may not be preserved.
-func (s LINQ) Order() LINQ {
+// booleans against each other, strings against each other, and nils against all types). Order among equal items is preserved.
+func (s LINQ) Order() OrderedLINQ {
 	return s.OrderPD(nil, false)
 }
 
-// Returns the sequence ordered using the given comparison function. Order among equal items may not be preserved.
-func (s LINQ) OrderP(cmp LessThanFunc) LINQ {
+// Returns the sequence ordered using the given comparison function. Order among equal items is preserved.
+func (s LINQ) OrderP(cmp LessThanFunc) OrderedLINQ {
 	return s.OrderPD(cmp, false)
 }
 
-// Returns the sequence ordered using the given comparison function. Order among equal items may not be preserved.
+// Returns the sequence ordered using the given comparison function. Order among equal items is preserved.
 // If the comparer is strongly typed, it will be called via reflection.
-func (s LINQ) OrderR(cmp T) LINQ {
+func (s LINQ) OrderR(cmp T) OrderedLINQ {
 	return s.OrderRD(cmp, false)
 }
 
 // Returns the sequence ordered in reverse using the default comparison function (which can compare all numerics against each other,
-// booleans against each other, strings against each other, and nils against all types). Order among equal items may not be preserved.
-func (s LINQ) OrderDescending() LINQ {
+// booleans against each other, strings against each other, and nils against all types). Order among equal items is preserved.
+func (s LINQ) OrderDescending() OrderedLINQ {
 	return s.OrderPD(nil, true)
 }
 
-// Returns the sequence ordered in reverse using the given comparison function. Order among equal items may not be preserved.
-func (s LINQ) OrderDescendingP(cmp LessThanFunc) LINQ {
+// Returns the sequence ordered in reverse using the given comparison function. Order among equal items is preserved.
+func (s LINQ) OrderDescendingP(cmp LessThanFunc) OrderedLINQ {
 	return s.OrderPD(cmp, true)
 }
 
-// Returns the sequence ordered in reverse using the given comparison function. Order among equal items may not be preserved.
+// Returns the sequence ordered in reverse using the given comparison function. Order among equal items is preserved.
 // If the comparer is strongly typed, it will be called via reflection.
-func (s LINQ) OrderDescendingR(cmp T) LINQ {
+func (s LINQ) OrderDescendingR(cmp T) OrderedLINQ {
 	return s.OrderRD(cmp, true)
 }
 
 // Returns the sequence ordered using the given comparison function (or the generic comparison function if nil).
-// Order among equal items may not be preserved.
-func (s LINQ) OrderPD(cmp LessThanFunc, reverse bool) LINQ {
-	if cmp == nil {
-		cmp = GenericLessThan
-	}
-	d := orderData{cmp: cmp}
-	return FromSequenceFunction(func() IteratorFunc {
-		index := 0
-		return func() (T, bool) {
-			if d.items == nil { // on the first call to Next, generate and sort the data
-				d.items = ToSlice(s.Sequence)
-				var sorter sort.Interface = &d
-				if reverse {
-					sorter = sort.Reverse(sorter)
-				}
-				sort.Sort(sorter)
-			}
-
-			if index < len(d.items) {
-				item := d.items[index]
-				index++
-				return item, true
-			}
-			return nil, false
-		}
-	})
+// Order among equal items is preserved.
+func (s LINQ) OrderPD(cmp LessThanFunc, reverse bool) OrderedLINQ {
+	return s.orderBy(makeSortKey(nil, cmp, reverse))
 }
 
 // Returns the sequence ordered using the given comparison function (or the generic comparison function if nil).
-// Order among equal items may not be preserved. If the comparer is strongly typed, it will be called via reflection.
-func (s LINQ) OrderRD(cmp T, reverse bool) LINQ {
+// Order among equal items is preserved. If the comparer is strongly typed, it will be called via reflection.
+func (s LINQ) OrderRD(cmp T, reverse bool) OrderedLINQ {
 	return s.OrderPD(genericLessThanFunc(cmp), reverse)
 }
 
 // Returns the sequence ordered by key using the default comparison function (which can compare all numerics against each other,
-// booleans against each other, strings against each other, and nils against all types). Order among equal items may not be preserved.
-func (s LINQ) OrderBy(keySelector Selector) LINQ {
+// booleans against each other, strings against each other, and nils against all types). Order among equal items is preserved.
+func (s LINQ) OrderBy(keySelector Selector) OrderedLINQ {
 	return s.OrderByPD(keySelector, nil, false)
 }
 
-// Returns the sequence ordered by key using the given comparison function. Order among equal items may not be preserved.
-func (s LINQ) OrderByP(keySelector Selector, cmp LessThanFunc) LINQ {
+// Returns the sequence ordered by key using the given comparison function. Order among equal items is preserved.
+func (s LINQ) OrderByP(keySelector Selector, cmp LessThanFunc) OrderedLINQ {
 	return s.OrderByPD(keySelector, cmp, false)
 }
 
-// Returns the sequence ordered by key using the given comparison function. Order among equal items may not be preserved.
+// Returns the sequence ordered by key using the given comparison function. Order among equal items is preserved.
 // If either function is strongly typed, it will be called via reflection.
-func (s LINQ) OrderByPR(keySelector T, cmp T) LINQ {
+func (s LINQ) OrderByPR(keySelector T, cmp T) OrderedLINQ {
 	return s.OrderByRD(keySelector, cmp, false)
 }
 
 // Returns the sequence ordered by key using the default comparison function (which can compare all numerics against each other,
-// booleans against each other, strings against each other, and nils against all types). Order among equal items may not be preserved.
+// booleans against each other, strings against each other, and nils against all types). Order among equal items is preserved.
 // If the selector is strongly typed, it will be called via reflection.
-func (s LINQ) OrderByR(keySelector T) LINQ {
+func (s LINQ) OrderByR(keySelector T) OrderedLINQ {
 	return s.OrderByRD(keySelector, nil, false)
 }
 
 // Returns the sequence ordered by key in reverse using the default comparison function (which can compare all numerics against each
-// other, booleans against each other, strings against each other, and nils against all types). Order among equal items may not be
+// other, booleans against each other, strings against each other, and nils against all types). Order among equal items is
 // preserved.
-func (s LINQ) OrderByDescending(keySelector Selector) LINQ {
+func (s LINQ) OrderByDescending(keySelector Selector) OrderedLINQ {
 	return s.OrderByPD(keySelector, nil, true)
 }
 
-// Returns the sequence ordered by key in reverse using the given comparison function. Order among equal items may not be preserved.
-func (s LINQ) OrderByDescendingP(keySelector Selector, cmp LessThanFunc) LINQ {
+// Returns the sequence ordered by key in reverse using the given comparison function. Order among equal items is preserved.
+func (s LINQ) OrderByDescendingP(keySelector Selector, cmp LessThanFunc) OrderedLINQ {
 	return s.OrderByPD(keySelector, cmp, true)
 }
 
-// Returns the sequence ordered by key in reverse using the given comparison function. Order among equal items may not be preserved.
+// Returns the sequence ordered by key in reverse using the given comparison function. Order among equal items is preserved.
 // If either function is strongly typed, it will be called via reflection.
-func (s LINQ) OrderByDescendingPR(keySelector T, cmp T) LINQ {
+func (s LINQ) OrderByDescendingPR(keySelector T, cmp T) OrderedLINQ {
 	return s.OrderByRD(keySelector, cmp, true)
 }
 
 // Returns the sequence ordered by key in reverse using the default comparison function (which can compare all numerics against each
-// other, booleans against each other, strings against each other, and nils against all types). Order among equal items may not be
+// other, booleans against each other, strings against each other, and nils against all types). Order among equal items is
 // preserved.
 // If the selector is strongly typed, it will be called via reflection.
-func (s LINQ) OrderByDescendingR(keySelector T) LINQ {
+func (s LINQ) OrderByDescendingR(keySelector T) OrderedLINQ {
 	return s.OrderByRD(keySelector, nil, true)
 }
 
-// Returns the sequence ordered by key using the given comparison function. Order among equal items may not be preserved.
-func (s LINQ) OrderByPD(keySelector Selector, cmp LessThanFunc, reverse bool) LINQ {
-	if cmp == nil {
-		cmp = GenericLessThan
-	}
-	d := orderByData{cmp: cmp}
-	return FromSequenceFunction(func() IteratorFunc {
+// Returns the sequence ordered by key using the given comparison function. Order among equal items is preserved.
+func (s LINQ) OrderByPD(keySelector Selector, cmp LessThanFunc, reverse bool) OrderedLINQ {
+	return s.orderBy(makeSortKey(keySelector, cmp, reverse))
+}
+
+// Returns the sequence ordered by key using the given comparison function. Order among equal items is preserved.
+// If either function is strongly typed, it will be called via reflection.
+func (s LINQ) OrderByRD(keySelector T, cmp T, reverse bool) OrderedLINQ {
+	return s.OrderByPD(genericSelectorFunc(keySelector), genericLessThanFunc(cmp), reverse)
+}
+
+// An OrderedLINQ is a sequence that has been ordered by Order, OrderBy, or one of their variants. Its items can be further ordered
+// with ThenBy and its variants, which order items that are equal according to the previous orderings.
+type OrderedLINQ struct {
+	LINQ
+	source LINQ
+	keys   []sortKey
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key using the default comparison function.
+func (s OrderedLINQ) ThenBy(keySelector Selector) OrderedLINQ {
+	return s.ThenByPD(keySelector, nil, false)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key using the given comparison function.
+func (s OrderedLINQ) ThenByP(keySelector Selector, cmp LessThanFunc) OrderedLINQ {
+	return s.ThenByPD(keySelector, cmp, false)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key using the given comparison function.
+// If either function is strongly typed, it will be called via reflection.
+func (s OrderedLINQ) ThenByPR(keySelector T, cmp T) OrderedLINQ {
+	return s.ThenByRD(keySelector, cmp, false)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key using the default comparison function.
+// If the selector is strongly typed, it will be called via reflection.
+func (s OrderedLINQ) ThenByR(keySelector T) OrderedLINQ {
+	return s.ThenByRD(keySelector, nil, false)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key in reverse using the default comparison function.
+func (s OrderedLINQ) ThenByDescending(keySelector Selector) OrderedLINQ {
+	return s.ThenByPD(keySelector, nil, true)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key in reverse using the given comparison function.
+func (s OrderedLINQ) ThenByDescendingP(keySelector Selector, cmp LessThanFunc) OrderedLINQ {
+	return s.ThenByPD(keySelector, cmp, true)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key in reverse using the given comparison function.
+// If either function is strongly typed, it will be called via reflection.
+func (s OrderedLINQ) ThenByDescendingPR(keySelector T, cmp T) OrderedLINQ {
+	return s.ThenByRD(keySelector, cmp, true)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key in reverse using the default comparison function.
+// If the selector is strongly typed, it will be called via reflection.
+func (s OrderedLINQ) ThenByDescendingR(keySelector T) OrderedLINQ {
+	return s.ThenByRD(keySelector, nil, true)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key using the given comparison function (or the generic
+// comparison function if nil). All the keys are applied in a single sort.
+func (s OrderedLINQ) ThenByPD(keySelector Selector, cmp LessThanFunc, reverse bool) OrderedLINQ {
+	keys := make([]sortKey, len(s.keys), len(s.keys)+1) // copy the keys so that orderings derived from the same sequence don't interfere
+	copy(keys, s.keys)
+	return s.source.orderBy(append(keys, makeSortKey(keySelector, cmp, reverse))...)
+}
+
+// Returns the sequence ordered first by its existing ordering and then by key using the given comparison function (or the generic
+// comparison function if nil). If either function is strongly typed, it will be called via reflection.
+func (s OrderedLINQ) ThenByRD(keySelector T, cmp T, reverse bool) OrderedLINQ {
+	return s.ThenByPD(genericSelectorFunc(keySelector), genericLessThanFunc(cmp), reverse)
+}
+
+// Returns the sequence ordered by the given keys, which are applied in order.
+func (s LINQ) orderBy(keys ...sortKey) OrderedLINQ {
+	var d *sortData
+	sorted := FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		index := 0
-		return func() (T, bool) {
-			if d.items == nil { // on the first call to Next(), sort the data
-				d.items = ToSlice(s.Sequence)
-				d.keys = make([]T, len(d.items))
-				for ind, v := range d.items {
-					d.keys[ind] = keySelector(v)
+		next := func() (T, bool, error) {
+			if d == nil { // on the first call to Next, generate and sort the data
+				items, err := TryToSlice(s.Sequence)
+				if err != nil {
+					return nil, false, err
 				}
-				var sorter sort.Interface = &d
-				if reverse {
-					sorter = sort.Reverse(sorter)
-				}
-				sort.Sort(sorter)
-				d.keys = nil
+				d = newSortData(items, keys)
+				sort.Stable(d)
+				d.keys = nil // the keys are no longer needed
 			}
 
 			if index < len(d.items) {
 				item := d.items[index]
 				index++
-				return item, true
+				return item, true, nil
 			}
-			return nil, false
+			return nil, false, nil
 		}
+		return next, nil
 	})
+	return OrderedLINQ{sorted, s, keys}
 }
 
-// Returns the sequence ordered by key using the given comparison function. Order among equal items may not be preserved.
-// If either function is strongly typed, it will be called via reflection.
-func (s LINQ) OrderByRD(keySelector T, cmp T, reverse bool) LINQ {
-	return s.OrderByPD(genericSelectorFunc(keySelector), genericLessThanFunc(cmp), reverse)
+// A sortKey describes how to order items by a key.
+type sortKey struct {
+	selector Selector     // the function that selects the key from an item, or nil to compare the items themselves
+	cmp      LessThanFunc // the comparison function, which has been reversed if the ordering is descending
 }
 
-type orderByData struct {
-	keys, items []T
-	cmp         LessThanFunc
+// Returns a sortKey that orders items by the key returned from the selector (or the item itself if the selector is nil) using the
+// given comparison function (or the generic comparison function if nil). The comparison function is reversed rather than the
+// sort so that equal items stay in order.
+func makeSortKey(keySelector Selector, cmp LessThanFunc, reverse bool) sortKey {
+	if cmp == nil {
+		cmp = GenericLessThan
+	}
+	if reverse {
+		less := cmp
+		cmp = func(a, b T) bool { return less(b, a) }
+	}
+	return sortKey{keySelector, cmp}
 }
 
-func (d *orderByData) Len() int {
-	return len(d.items)
+// Implements sort.Interface to sort items by one or more keys, which are selected once per item.
+type sortData struct {
+	items []T
+	keys  [][]T // the keys of each item for each sortKey, or nil if the sortKey compares the items themselves
+	cmps  []LessThanFunc
 }
 
-func (d *orderByData) Less(ai, bi int) bool {
-	return d.cmp(d.keys[ai], d.keys[bi])
+func newSortData(items []T, keys []sortKey) *sortData {
+	d := &sortData{items: items, keys: make([][]T, len(keys)), cmps: make([]LessThanFunc, len(keys))}
+	for k, key := range keys {
+		d.cmps[k] = key.cmp
+		if key.selector != nil {
+			d.keys[k] = make([]T, len(items))
+			for i, v := range items {
+				d.keys[k][i] = key.selector(v)
+			}
+		}
+	}
+	return d
 }
 
-func (d *orderByData) Swap(ai, bi int) {
-	d.items[ai], d.items[bi] = d.items[bi], d.items[ai]
-	d.keys[ai], d.keys[bi] = d.keys[bi], d.keys[ai]
+func (d *sortData) Len() int {
+	return len(d.items)
 }
 
-type orderData struct {
-	cmp   LessThanFunc
-	items []T
+func (d *sortData) Less(ai, bi int) bool {
+	return d.lessThan(ai, d, bi)
 }
 
-func (d *orderData) Len() int {
-	return len(d.items)
+func (d *sortData) Swap(ai, bi int) {
+	d.items[ai], d.items[bi] = d.items[bi], d.items[ai]
+	for _, keys := range d.keys {
+		if keys != nil {
+			keys[ai], keys[bi] = keys[bi], keys[ai]
+		}
+	}
 }
 
-func (d *orderData) Less(ai, bi int) bool {
-	return d.cmp(d.items[ai], d.items[bi])
+// Returns the key of the item at the given index for the given sortKey.
+func (d *sortData) key(k, i int) T {
+	if d.keys[k] == nil {
+		return d.items[i]
+	}
+	return d.keys[k][i]
 }
 
-func (d *orderData) Swap(ai, bi int) {
-	d.items[ai], d.items[bi] = d.items[bi], d.items[ai]
+// Determines whether the item at index ai should be ordered before the item at index bi in another sortData with the same keys.
+func (d *sortData) lessThan(ai int, o *sortData, bi int) bool {
+	for k, cmp := range d.cmps {
+		a, b := d.key(k, ai), o.key(k, bi)
+		if cmp(a, b) {
+			return true
+		} else if k == len(d.cmps)-1 || cmp(b, a) { // if a > b, or a == b according to the last key...
+			return false
+		}
+	}
+	return false
 }
@@ -20,34 +20,576 @@ Foundation, Inc., 59 Temple Place - Suite 330, Boston, MA  02111-1307, USA.
 package linq
 
 import (
+	"container/heap"
+	"context"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 
 	. "github.com/AdamMil/go/collections"
 )
 
+// Aggregates the items in the sequence in parallel. The items are divided among up to 'threads' goroutines (or the number of CPU
+// cores if 'threads' is zero), each of which starts with a seed returned from seedFactory and passes it to the accumulate function
+// along with an item, then passes the result and another item, and so on. The partial results are then merged with the combine
+// function, in an unspecified order, and the final result is returned. If the sequence is empty, a new seed is returned. Panics from
+// the functions are propagated after all the goroutines finish.
+func (s LINQ) ParallelAggregate(threads int, seedFactory func() T, accumulate, combine Aggregator) T {
+	if result, ok := s.tryParallelAggregate(threads, seedFactory, accumulate, combine); ok {
+		return result
+	}
+	return seedFactory()
+}
+
+// Counts the number of items in the sequence matching the given predicate, evaluating the predicate in parallel on up to 'threads'
+// goroutines (or the number of CPU cores if 'threads' is zero).
+func (s LINQ) ParallelCount(threads int, pred Predicate) int {
+	count := func(n, item T) T {
+		if pred(item) {
+			n = n.(int) + 1
+		}
+		return n
+	}
+	return s.ParallelAggregate(threads, func() T { return 0 }, count, func(a, b T) T { return a.(int) + b.(int) }).(int)
+}
+
+// Counts the number of items in the sequence matching the given predicate, evaluating the predicate in parallel on up to 'threads'
+// goroutines (or the number of CPU cores if 'threads' is zero). If the predicate is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelCountR(threads int, pred T) int {
+	return s.ParallelCount(threads, genericPredicateFunc(pred))
+}
+
 // Calls an action for each item in the sequence. The items are processed in parallel, with up to 'threads' items being processed at a
 // time. If 'threads' is zero, the number of CPU cores is used. If 'threads' is -1, no limit is applied. Due to the parallelism, the
-// items may not be processed in order.
+// items may not be processed in order. The iterator is closed afterward, even if an action panics.
 func (s LINQ) ParallelForEach(threads int, action Action) LINQ {
-	var ex T                     // any panic value that we recovered. we'll stop working if we catch one, and repanic at the end
+	i := s.Iterator()
+	defer finishIterators(i)
+	parallelForEach(context.Background(), i, threads, action)
+	return s
+}
+
+// Calls an action for each item in the sequence like ParallelForEach, but stops starting new actions when the context is done. The
+// actions receive the context so that those in progress can observe it, and are allowed to finish. Returns the context's error if
+// it's done, or else the error that ended the iteration or from closing the iterator.
+func (s LINQ) ParallelForEachCtx(ctx context.Context, threads int, action func(context.Context, T)) error {
+	i := s.Iterator()
+	defer CloseIterator(i) // close the iterator even if an action panics
+	parallelForEach(ctx, i, threads, func(item T) { action(ctx, item) })
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return FinishIterator(i)
+}
+
+// Calls an action for each item in the sequence. The items are processed in parallel, with up to 'threads' items being processed at a
+// time. If 'threads' is zero, the number of CPU cores is used. If 'threads' is -1, no limit is applied. Due to the parallelism, the
+// items may not be processed in order. If the action is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelForEachR(threads int, action T) LINQ {
+	return s.ParallelForEach(threads, genericActionFunc(action))
+}
+
+// Groups the items in the sequence like GroupBy, but divides the items among up to 'threads' goroutines (or the number of CPU cores
+// if 'threads' is zero), which select keys and group the items in parallel, and then merges the groups. Due to the parallelism,
+// neither the order of the groups nor the order of items within each group is preserved.
+func (s LINQ) ParallelGroupBy(threads int, keySelector Selector) LINQ {
+	accumulate := func(groups, item T) T {
+		addToGroup(groups.(*OrderedDictionary), keySelector(item), item)
+		return groups
+	}
+	combine := func(a, b T) T {
+		groups := a.(*OrderedDictionary)
+		for i := b.(*OrderedDictionary).Iterator(); i.Next(); {
+			p := i.Current().(Pair)
+			for _, item := range p.Value.([]T) {
+				addToGroup(groups, p.Key, item)
+			}
+		}
+		return groups
+	}
+	groups := s.ParallelAggregate(threads, func() T { return MakeOrderedDictionary(0) }, accumulate, combine)
+	return From(makeLookup(groups.(*OrderedDictionary)))
+}
+
+// Groups the items in the sequence like GroupBy, but divides the items among up to 'threads' goroutines (or the number of CPU cores
+// if 'threads' is zero), which select keys and group the items in parallel, and then merges the groups. Due to the parallelism,
+// neither the order of the groups nor the order of items within each group is preserved.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelGroupByR(threads int, keySelector T) LINQ {
+	return s.ParallelGroupBy(threads, genericSelectorFunc(keySelector))
+}
+
+// Returns the item from the sequence with the greatest value according to the default comparison function, comparing items in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). If several items are equally great, it's
+// unspecified which is returned. If the sequence is empty, the function panics.
+func (s LINQ) ParallelMax(threads int) T {
+	return s.ParallelMaxP(threads, GenericLessThan)
+}
+
+// Returns the item from the sequence with the greatest value according to the given comparison function, comparing items in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). If several items are equally great, it's
+// unspecified which is returned. If the sequence is empty, the function panics.
+func (s LINQ) ParallelMaxP(threads int, cmp LessThanFunc) T {
+	return s.mustParallelAggregate(threads, maxf(cmp))
+}
+
+// Returns the item from the sequence with the greatest value according to the given comparison function, comparing items in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). If several items are equally great, it's
+// unspecified which is returned. If the sequence is empty, the function panics.
+// If the comparer is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelMaxR(threads int, cmp T) T {
+	return s.ParallelMaxP(threads, genericLessThanFunc(cmp))
+}
+
+// Returns the item from the sequence with the least value according to the default comparison function, comparing items in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). If several items are equally small, it's
+// unspecified which is returned. If the sequence is empty, the function panics.
+func (s LINQ) ParallelMin(threads int) T {
+	return s.ParallelMinP(threads, GenericLessThan)
+}
+
+// Returns the item from the sequence with the least value according to the given comparison function, comparing items in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). If several items are equally small, it's
+// unspecified which is returned. If the sequence is empty, the function panics.
+func (s LINQ) ParallelMinP(threads int, cmp LessThanFunc) T {
+	return s.mustParallelAggregate(threads, minf(cmp))
+}
+
+// Returns the item from the sequence with the least value according to the given comparison function, comparing items in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). If several items are equally small, it's
+// unspecified which is returned. If the sequence is empty, the function panics.
+// If the comparer is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelMinR(threads int, cmp T) T {
+	return s.ParallelMinP(threads, genericLessThanFunc(cmp))
+}
+
+// Returns the sequence ordered using the default comparison function (which can compare all numerics against each other,
+// booleans against each other, strings against each other, and nils against all types). The items are divided into partitions,
+// which are sorted in parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero) and then merged.
+// Order among equal items is preserved.
+func (s LINQ) ParallelOrder(threads int) LINQ {
+	return s.ParallelOrderPD(threads, nil, false)
+}
+
+// Returns the sequence ordered in reverse using the default comparison function, sorting partitions of the sequence in parallel on up
+// to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+func (s LINQ) ParallelOrderDescending(threads int) LINQ {
+	return s.ParallelOrderPD(threads, nil, true)
+}
+
+// Returns the sequence ordered in reverse using the given comparison function, sorting partitions of the sequence in parallel on up
+// to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+func (s LINQ) ParallelOrderDescendingP(threads int, cmp LessThanFunc) LINQ {
+	return s.ParallelOrderPD(threads, cmp, true)
+}
+
+// Returns the sequence ordered in reverse using the given comparison function, sorting partitions of the sequence in parallel on up
+// to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+// If the comparer is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderDescendingR(threads int, cmp T) LINQ {
+	return s.ParallelOrderRD(threads, cmp, true)
+}
+
+// Returns the sequence ordered using the given comparison function, sorting partitions of the sequence in parallel on up to
+// 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+func (s LINQ) ParallelOrderP(threads int, cmp LessThanFunc) LINQ {
+	return s.ParallelOrderPD(threads, cmp, false)
+}
+
+// Returns the sequence ordered using the given comparison function (or the generic comparison function if nil), sorting partitions
+// of the sequence in parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal
+// items is preserved.
+func (s LINQ) ParallelOrderPD(threads int, cmp LessThanFunc, reverse bool) LINQ {
+	return s.parallelOrder(threads, makeSortKey(nil, cmp, reverse))
+}
+
+// Returns the sequence ordered using the given comparison function, sorting partitions of the sequence in parallel on up to
+// 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+// If the comparer is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderR(threads int, cmp T) LINQ {
+	return s.ParallelOrderRD(threads, cmp, false)
+}
+
+// Returns the sequence ordered using the given comparison function (or the generic comparison function if nil), sorting partitions
+// of the sequence in parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal
+// items is preserved. If the comparer is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderRD(threads int, cmp T, reverse bool) LINQ {
+	return s.ParallelOrderPD(threads, genericLessThanFunc(cmp), reverse)
+}
+
+// Returns the sequence ordered by key using the default comparison function. The items are divided into partitions, whose keys are
+// selected and which are sorted in parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero), and
+// then the partitions are merged. Order among equal items is preserved.
+func (s LINQ) ParallelOrderBy(threads int, keySelector Selector) LINQ {
+	return s.ParallelOrderByPD(threads, keySelector, nil, false)
+}
+
+// Returns the sequence ordered by key in reverse using the default comparison function, sorting partitions of the sequence in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+func (s LINQ) ParallelOrderByDescending(threads int, keySelector Selector) LINQ {
+	return s.ParallelOrderByPD(threads, keySelector, nil, true)
+}
+
+// Returns the sequence ordered by key in reverse using the given comparison function, sorting partitions of the sequence in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+func (s LINQ) ParallelOrderByDescendingP(threads int, keySelector Selector, cmp LessThanFunc) LINQ {
+	return s.ParallelOrderByPD(threads, keySelector, cmp, true)
+}
+
+// Returns the sequence ordered by key in reverse using the given comparison function, sorting partitions of the sequence in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+// If either function is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderByDescendingPR(threads int, keySelector T, cmp T) LINQ {
+	return s.ParallelOrderByRD(threads, keySelector, cmp, true)
+}
+
+// Returns the sequence ordered by key in reverse using the default comparison function, sorting partitions of the sequence in
+// parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderByDescendingR(threads int, keySelector T) LINQ {
+	return s.ParallelOrderByRD(threads, keySelector, nil, true)
+}
+
+// Returns the sequence ordered by key using the given comparison function, sorting partitions of the sequence in parallel on up to
+// 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+func (s LINQ) ParallelOrderByP(threads int, keySelector Selector, cmp LessThanFunc) LINQ {
+	return s.ParallelOrderByPD(threads, keySelector, cmp, false)
+}
+
+// Returns the sequence ordered by key using the given comparison function (or the generic comparison function if nil), sorting
+// partitions of the sequence in parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order
+// among equal items is preserved.
+func (s LINQ) ParallelOrderByPD(threads int, keySelector Selector, cmp LessThanFunc, reverse bool) LINQ {
+	return s.parallelOrder(threads, makeSortKey(keySelector, cmp, reverse))
+}
+
+// Returns the sequence ordered by key using the given comparison function, sorting partitions of the sequence in parallel on up to
+// 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+// If either function is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderByPR(threads int, keySelector T, cmp T) LINQ {
+	return s.ParallelOrderByRD(threads, keySelector, cmp, false)
+}
+
+// Returns the sequence ordered by key using the default comparison function, sorting partitions of the sequence in parallel on up
+// to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order among equal items is preserved.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderByR(threads int, keySelector T) LINQ {
+	return s.ParallelOrderByRD(threads, keySelector, nil, false)
+}
+
+// Returns the sequence ordered by key using the given comparison function (or the generic comparison function if nil), sorting
+// partitions of the sequence in parallel on up to 'threads' goroutines (or the number of CPU cores if 'threads' is zero). Order
+// among equal items is preserved. If either function is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelOrderByRD(threads int, keySelector T, cmp T, reverse bool) LINQ {
+	return s.ParallelOrderByPD(threads, genericSelectorFunc(keySelector), genericLessThanFunc(cmp), reverse)
+}
+
+// Returns the sequence with each item transformed by a selector function. Up to maxThreads transformations may happen in parallel.
+// (If maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the items may be returned out of order. (Use
+// ParallelSelectOrdered to preserve the order.) Closing the iterator stops reading from the source and waits for transformations in
+// progress to finish.
+func (s LINQ) ParallelSelect(maxThreads int, selector Selector) LINQ {
+	if maxThreads = checkMaxThreads(maxThreads); maxThreads == 1 { // optimize the single-core case
+		return s.Select(selector)
+	}
+	return s.parallelSelect(context.Background(), maxThreads, selector)
+}
+
+// Returns the sequence with each item transformed by a selector function like ParallelSelect, but stops starting new
+// transformations when the context is done. The selector receives the context so that transformations in progress can observe it.
+// When the context is done, the iterator fails with the context's error after returning the items already transformed.
+func (s LINQ) ParallelSelectCtx(ctx context.Context, maxThreads int, selector func(context.Context, T) T) LINQ {
+	return s.parallelSelect(ctx, checkMaxThreads(maxThreads), func(item T) T { return selector(ctx, item) })
+}
+
+// Returns the sequence with each item transformed by a selector function, in the same order as the source. Up to maxThreads
+// transformations may happen in parallel. (If maxThreads is zero, the number of CPUs is used.) To limit memory usage, no more than
+// 'window' items are read from the source before they've been returned, so an item that's slow to transform can hold up the
+// others. If window is zero, twice the number of threads is used. Closing the iterator stops reading from the source and waits for
+// transformations in progress to finish.
+func (s LINQ) ParallelSelectOrdered(maxThreads, window int, selector Selector) LINQ {
+	maxThreads, window = checkMaxThreads(maxThreads), checkWindow(window)
+	if maxThreads == 1 { // optimize the single-core case
+		return s.Select(selector)
+	}
+	return s.parallelSelectOrdered(context.Background(), maxThreads, window, selector)
+}
+
+// Returns the sequence with each item transformed by a selector function like ParallelSelectOrdered, but stops starting new
+// transformations when the context is done. The selector receives the context so that transformations in progress can observe it.
+// When the context is done, the iterator fails with the context's error after returning the items already transformed.
+func (s LINQ) ParallelSelectOrderedCtx(ctx context.Context, maxThreads, window int, selector func(context.Context, T) T) LINQ {
+	maxThreads, window = checkMaxThreads(maxThreads), checkWindow(window)
+	return s.parallelSelectOrdered(ctx, maxThreads, window, func(item T) T { return selector(ctx, item) })
+}
+
+// Returns the sequence with each item transformed by a selector function, in the same order as the source. Up to maxThreads
+// transformations may happen in parallel. (If maxThreads is zero, the number of CPUs is used.) No more than 'window' items are read
+// from the source before they've been returned. If window is zero, twice the number of threads is used.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelSelectOrderedR(maxThreads, window int, selector T) LINQ {
+	return s.ParallelSelectOrdered(maxThreads, window, genericSelectorFunc(selector))
+}
+
+// Returns the sequence with each item transformed by a selector function. Up to maxThreads transformations may happen in parallel.
+// (If maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the items may be returned out of order.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelSelectR(maxThreads int, selector T) LINQ {
+	return s.ParallelSelect(maxThreads, genericSelectorFunc(selector))
+}
+
+// Transforms each item into a sequence using the selector - nils are considered empty sequences - and returns a new sequence that
+// is the concatenation of all the sequences. Up to maxThreads items may be transformed in parallel, and each sequence is read into
+// memory by the thread that created it. (If maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the sequences
+// may be concatenated out of order, although the items within each sequence stay in order. (Use ParallelSelectManyOrdered to
+// preserve the order.) Closing the iterator stops reading from the source and waits for transformations in progress to finish.
+func (s LINQ) ParallelSelectMany(maxThreads int, selector Selector) LINQ {
+	if maxThreads = checkMaxThreads(maxThreads); maxThreads == 1 { // optimize the single-core case
+		return s.SelectMany(selector)
+	}
+	return s.parallelSelect(context.Background(), maxThreads, bufferedSelector(selector)).SelectMany(selectSequence)
+}
+
+// Transforms each item into a sequence using the selector - nils are considered empty sequences - and returns a new sequence that
+// is the concatenation of all the sequences, in the same order as the source. Up to maxThreads items may be transformed in
+// parallel, and each sequence is read into memory by the thread that created it. (If maxThreads is zero, the number of CPUs is
+// used.) No more than 'window' items are read from the source before their sequences have been returned. If window is zero, twice
+// the number of threads is used.
+func (s LINQ) ParallelSelectManyOrdered(maxThreads, window int, selector Selector) LINQ {
+	maxThreads, window = checkMaxThreads(maxThreads), checkWindow(window)
+	if maxThreads == 1 { // optimize the single-core case
+		return s.SelectMany(selector)
+	}
+	return s.parallelSelectOrdered(context.Background(), maxThreads, window, bufferedSelector(selector)).SelectMany(selectSequence)
+}
+
+// Transforms each item into a sequence using the selector - nils are considered empty sequences - and returns a new sequence that
+// is the concatenation of all the sequences, in the same order as the source. Up to maxThreads items may be transformed in
+// parallel. (If maxThreads is zero, the number of CPUs is used.) No more than 'window' items are read from the source before their
+// sequences have been returned. If window is zero, twice the number of threads is used.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelSelectManyOrderedR(maxThreads, window int, selector T) LINQ {
+	return s.ParallelSelectManyOrdered(maxThreads, window, genericSelectorFunc(selector))
+}
+
+// Transforms each item into a sequence using the selector - nils are considered empty sequences - and returns a new sequence that
+// is the concatenation of all the sequences. Up to maxThreads items may be transformed in parallel. (If maxThreads is zero, the
+// number of CPUs is used.) Due to the parallelism, the sequences may be concatenated out of order.
+// If the selector is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelSelectManyR(maxThreads int, selector T) LINQ {
+	return s.ParallelSelectMany(maxThreads, genericSelectorFunc(selector))
+}
+
+// Returns the sum of the items in the sequence like Sum, but adds the items in parallel on up to 'threads' goroutines (or the number
+// of CPU cores if 'threads' is zero). If the sequence is empty, the function panics.
+func (s LINQ) ParallelSum(threads int) T {
+	return normalizeSum(s.mustParallelAggregate(threads, genericAdd))
+}
+
+// Returns the items in the sequence that match the given predicate. Up to maxThreads predicates may be evaluated in parallel. (If
+// maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the items may be returned out of order. (Use
+// ParallelWhereOrdered to preserve the order.) Closing the iterator stops reading from the source and waits for predicates in
+// progress to finish.
+func (s LINQ) ParallelWhere(maxThreads int, pred Predicate) LINQ {
+	if maxThreads = checkMaxThreads(maxThreads); maxThreads == 1 { // optimize the single-core case
+		return s.Where(pred)
+	}
+	return filterItems(s.parallelSelect(context.Background(), maxThreads, filterSelector(pred)))
+}
+
+// Returns the items in the sequence that match the given predicate, in the same order as the source. Up to maxThreads predicates
+// may be evaluated in parallel. (If maxThreads is zero, the number of CPUs is used.) To limit memory usage, no more than 'window'
+// items are read from the source before they've been returned or rejected, so an item that's slow to evaluate can hold up the
+// others. If window is zero, twice the number of threads is used.
+func (s LINQ) ParallelWhereOrdered(maxThreads, window int, pred Predicate) LINQ {
+	maxThreads, window = checkMaxThreads(maxThreads), checkWindow(window)
+	if maxThreads == 1 { // optimize the single-core case
+		return s.Where(pred)
+	}
+	return filterItems(s.parallelSelectOrdered(context.Background(), maxThreads, window, filterSelector(pred)))
+}
+
+// Returns the items in the sequence that match the given predicate, in the same order as the source. Up to maxThreads predicates
+// may be evaluated in parallel. (If maxThreads is zero, the number of CPUs is used.) No more than 'window' items are read from the
+// source before they've been returned or rejected. If window is zero, twice the number of threads is used.
+// If the predicate is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelWhereOrderedR(maxThreads, window int, pred T) LINQ {
+	return s.ParallelWhereOrdered(maxThreads, window, genericPredicateFunc(pred))
+}
+
+// Returns the items in the sequence that match the given predicate. Up to maxThreads predicates may be evaluated in parallel. (If
+// maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the items may be returned out of order.
+// If the predicate is strongly typed, it will be called via reflection.
+func (s LINQ) ParallelWhereR(maxThreads int, pred T) LINQ {
+	return s.ParallelWhere(maxThreads, genericPredicateFunc(pred))
+}
+
+// Returns a selector that transforms an item into a sequence and reads the sequence into memory, so that the work of producing the
+// items happens on the thread that calls the selector. If reading the sequence fails, the returned sequence yields the items read
+// and then fails with the same error.
+func bufferedSelector(selector Selector) Selector {
+	return func(item T) T {
+		o := selector(item)
+		if o == nil {
+			return nil
+		}
+		var items []T
+		err := From(o).TryForEach(func(item T) { items = append(items, item) })
+		if err == nil {
+			return items
+		}
+		return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+			index := 0
+			next := func() (T, bool, error) {
+				if index == len(items) {
+					return nil, false, err
+				}
+				index++
+				return items[index-1], true, nil
+			}
+			return next, nil
+		})
+	}
+}
+
+// Implements the parallel ordering functions.
+func (s LINQ) parallelOrder(threads int, keys ...sortKey) LINQ {
+	threads = checkMaxThreads(threads)
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		var h *partitionHeap // the sorted partitions, arranged as a heap ordered by their next items
+		next := func() (T, bool, error) {
+			if h == nil { // on the first call to Next, sort the partitions
+				items, err := TryToSlice(s.Sequence)
+				if err != nil {
+					return nil, false, err
+				}
+				count := threads
+				if count > len(items) {
+					count = len(items)
+				}
+				parts := make([]*sortData, count)
+				Range(count).ParallelForEach(threads, func(p T) {
+					d := newSortData(items[p.(int)*len(items)/count:(p.(int)+1)*len(items)/count], keys)
+					sort.Stable(d)
+					parts[p.(int)] = d
+				})
+				h = &partitionHeap{parts: parts, heads: make([]int, count), order: make([]int, count)}
+				for p := range h.order {
+					h.order[p] = p
+				}
+				heap.Init(h)
+			}
+
+			// return the least item from the heads of the partitions, which is at the top of the heap
+			if h.Len() == 0 {
+				return nil, false, nil
+			}
+			p := h.order[0]
+			item := h.parts[p].items[h.heads[p]]
+			if h.heads[p]++; h.heads[p] == len(h.parts[p].items) { // if the partition is exhausted, remove it from the heap
+				heap.Pop(h)
+			} else { // otherwise, move it to its new place
+				heap.Fix(h, 0)
+			}
+			return item, true, nil
+		}
+		return next, nil
+	})
+}
+
+// A heap of sorted partitions ordered by their next items, used to merge the partitions. On ties, the earlier partition, whose
+// item came earlier in the sequence, is less. Only partitions that have items left are in the heap.
+type partitionHeap struct {
+	parts []*sortData
+	heads []int // the index of the next item in each partition
+	order []int // the indexes of the partitions in the heap
+}
+
+func (h *partitionHeap) Len() int {
+	return len(h.order)
+}
+
+func (h *partitionHeap) Less(a, b int) bool {
+	pa, pb := h.order[a], h.order[b]
+	if h.parts[pa].lessThan(h.heads[pa], h.parts[pb], h.heads[pb]) {
+		return true
+	} else if h.parts[pb].lessThan(h.heads[pb], h.parts[pa], h.heads[pa]) {
+		return false
+	}
+	return pa < pb
+}
+
+func (h *partitionHeap) Pop() interface{} {
+	p := h.order[len(h.order)-1]
+	h.order = h.order[:len(h.order)-1]
+	return p
+}
+
+func (h *partitionHeap) Push(p interface{}) {
+	h.order = append(h.order, p.(int))
+}
+
+func (h *partitionHeap) Swap(a, b int) {
+	h.order[a], h.order[b] = h.order[b], h.order[a]
+}
+
+// Returns the number of threads to use given a maximum, which is the number of CPUs if the maximum is zero.
+func checkMaxThreads(maxThreads int) int {
+	if maxThreads == 0 {
+		maxThreads = runtime.NumCPU()
+	} else if maxThreads < 0 {
+		panic("the number of threads must be non-negative")
+	}
+	return maxThreads
+}
+
+// Validates the size of a reorder window. Zero is returned as-is, meaning that a default size should be used.
+func checkWindow(window int) int {
+	if window < 0 {
+		panic("the window size must be non-negative")
+	}
+	return window
+}
+
+// Returns true if the given channel, which is the Done channel of a context, is closed.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Implements ParallelForEach and ParallelForEachCtx, calling the action for the items from the iterator until the iterator is
+// exhausted or the context is done.
+func parallelForEach(ctx context.Context, i Iterator, threads int, action Action) {
+	var ex T                // any panic value that we recovered. we'll stop working if we catch one, and repanic at the end
+	var exm sync.Mutex      // protects ex, which is written by the goroutines and read by the loops below
+	failed := func() bool { // returns whether an action has panicked
+		exm.Lock()
+		defer exm.Unlock()
+		return ex != nil
+	}
 	safeAction := func(item T) { // we don't want to get stuck in a deadlock if an action panics, so wrap it
 		defer func() {
 			if e := recover(); e != nil { // always recover from panics
+				exm.Lock()
 				ex = e // but save a panic value if any
+				exm.Unlock()
 			}
 		}()
 		action(item)
 	}
 
-	i, wg := s.Iterator(), sync.WaitGroup{}
+	done, wg := ctx.Done(), sync.WaitGroup{}
 	if threads < 0 { // if there's no limit to parallel processing...
 		process := func(item T) {
 			safeAction(item)
 			wg.Done()
 		}
-		for threads = 0; ex == nil && i.Next(); threads++ { // start a goroutine for every item
+		for threads = 0; !failed() && !isDone(done) && i.Next(); threads++ { // start a goroutine for every item
 			wg.Add(1)
 			go process(i.Current())
 		}
@@ -56,13 +598,18 @@ func (s LINQ) ParallelForEach(threads int, action Action) LINQ {
 			threads = runtime.NumCPU()
 		}
 		if threads == 1 { // optimize the single-core case
-			return s.ForEach(action)
+			for !isDone(done) && i.Next() {
+				action(i.Current())
+			}
+			return
 		}
 		c := make(chan T, threads) // so start a fixed number of goroutines...
 		runWorker := func() {
 			for { // each worker runs a loop
 				if item, ok := <-c; ok { // that tries to pull items off the item channel. if it gets an item...
-					safeAction(item) // it processes it
+					if !isDone(done) { // it processes it, unless the context is done
+						safeAction(item)
+					}
 				} else { // otherwise, if the item channel is closed
 					break // we're done
 				}
@@ -73,51 +620,46 @@ func (s LINQ) ParallelForEach(threads int, action Action) LINQ {
 		for i := 0; i < threads; i++ {
 			go runWorker()
 		}
-		for ex == nil && i.Next() { // ... and push items to them
-			c <- i.Current()
+	push:
+		for !failed() && !isDone(done) && i.Next() { // ... and push items to them until we're done
+			select {
+			case c <- i.Current():
+			case <-done:
+				break push
+			}
 		}
 		close(c) // let the workers know that we're out of items so they'll shut down
 	}
 	wg.Wait()      // then, in either case, wait for all the goroutines to complete
-	if ex != nil { // if an action panicked and we recovered, spread the panic
+	if ex != nil { // if an action panicked and we recovered, spread the panic. (the goroutines are done, so no lock is needed)
 		panic(ex)
 	}
-	return s
 }
 
-// Calls an action for each item in the sequence. The items are processed in parallel, with up to 'threads' items being processed at a
-// time. If 'threads' is zero, the number of CPU cores is used. If 'threads' is -1, no limit is applied. Due to the parallelism, the
-// items may not be processed in order. If the action is strongly typed, it will be called via reflection.
-func (s LINQ) ParallelForEachR(threads int, action T) LINQ {
-	return s.ParallelForEach(threads, genericActionFunc(action))
-}
-
-// Returns the sequence with each item transformed by a selector function. Up to maxThreads transformations may happen in parallel.
-// (If maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the items may be returned out of order.
-func (s LINQ) ParallelSelect(maxThreads int, selector Selector) LINQ {
-	if maxThreads == 0 {
-		maxThreads = runtime.NumCPU()
-	} else if maxThreads < 0 {
-		panic("the number of threads must be non-negative")
-	}
-	if maxThreads == 1 { // optimize the single-core case
-		return s.Select(selector)
-	}
-
-	return FromSequenceFunction(func() IteratorFunc {
-		i, c, m, threads, eos, ex := s.Iterator(), make(chan T, maxThreads), &sync.Mutex{}, int32(0), false, T(nil)
+// Implements ParallelSelect and ParallelSelectCtx, stopping the reading of new items from the source when the context is done.
+func (s LINQ) parallelSelect(ctx context.Context, maxThreads int, selector Selector) LINQ {
+	done := ctx.Done()
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		i, c, m, threads, eos, ex := s.Iterator(), make(chan T, maxThreads), &sync.Mutex{}, int32(0), int32(0), T(nil)
+		// closed indicates whether the iterator was closed, and it and ex (any recovered panic value) are protected by m. eos is set
+		// atomically to 1 when the channel is closed. wg tracks the running goroutines
+		closed, wg := false, &sync.WaitGroup{}
 		readItem := func() (T, bool) { // read and transform a single item from the source while handling any panics
 			m.Lock() // iterators are not thread-safe, so lock
 			locked := true
 			defer func() {
-				if locked { // ensure we unlock
-					m.Unlock()
-				}
 				if e := recover(); e != nil { // if a panic occurred, save the value for later
+					if !locked {
+						m.Lock()
+						locked = true
+					}
 					ex = e
 				}
+				if locked { // ensure we unlock
+					m.Unlock()
+				}
 			}()
-			if i.Next() { // now try reading an item
+			if !closed && !isDone(done) && i.Next() { // now try reading an item, unless the iterator was closed or we're done
 				item := i.Current()
 				m.Unlock() // unlock so the presumably slow selector doesn't run inside the lock
 				locked = false
@@ -131,12 +673,15 @@ func (s LINQ) ParallelSelect(maxThreads int, selector Selector) LINQ {
 				c <- item
 			}
 			if atomic.AddInt32(&threads, -1) == 0 && !ok { // last one out, shut the door!
-				eos = true // it's possible that no thread will get here even if we've reached the end of the sequence, but in the
-				close(c)   // worst case we just have to start one more thread to finally detect the end and close the channel.
+				// it's possible that no thread will get here even if we've reached the end of the sequence, but in the worst case we
+				// just have to start one more thread to finally detect the end and close the channel.
+				atomic.StoreInt32(&eos, 1)
+				close(c)
 			}
+			wg.Done()
 		}
-		return func() (T, bool) {
-			if !eos { // if we haven't reached the end of the sequence...
+		next := func() (T, bool, error) {
+			if atomic.LoadInt32(&eos) == 0 { // if we haven't reached the end of the sequence...
 				// top up the running threads. we have to be conservative so that no thread ever blocks on a full channel. otherwise,
 				// if the caller stops enumerating, the goroutine would never complete. so first read the number of threads and then
 				// read the number of items in the channel. (go guarantees left-to-right evaluation in this case.) each running thread
@@ -149,6 +694,7 @@ func (s LINQ) ParallelSelect(maxThreads int, selector Selector) LINQ {
 						available = 8
 					}
 					atomic.AddInt32(&threads, int32(available))
+					wg.Add(available)
 					for ; available > 0; available-- {
 						go processOne()
 					}
@@ -163,25 +709,240 @@ func (s LINQ) ParallelSelect(maxThreads int, selector Selector) LINQ {
 					if int(atomic.LoadInt32(&threads))+len(c) >= maxThreads { // if we've already got the maximum number of threads...
 						item, open = <-c // then block until we get an item or the channel is closed
 					} else { // otherwise, we could afford to start a new thread
-						if !eos { // if we haven't finished reading all the items from the source...
+						if atomic.LoadInt32(&eos) == 0 { // if we haven't finished reading all the items from the source...
 							atomic.AddInt32(&threads, 1) // start a new thread
+							wg.Add(1)
 							go processOne()
 						}
 						continue // loop to try reading again
 					}
 				}
-				if !open && ex != nil { // propagate any panic that occurred after we return all the queued items
-					panic(ex)
+				if open {
+					return item, true, nil // return the result
+				}
+				m.Lock()
+				e, err := ex, IteratorError(i)
+				m.Unlock()
+				if e != nil { // propagate any panic that occurred after we return all the queued items
+					panic(e)
+				} else if ctxErr := ctx.Err(); ctxErr != nil { // if we stopped because the context is done, report that
+					return nil, false, ctxErr
 				}
-				return item, open // return the result
+				return nil, false, err // otherwise, we're at the end, so report whether the source failed
 			}
 		}
+		closeFunc := func() error { // stop reading from the source, wait for running goroutines to finish, and close the source
+			m.Lock()
+			closed = true
+			m.Unlock()
+			wg.Wait()
+			return CloseIterator(i)
+		}
+		return next, closeFunc
 	})
 }
 
-// Returns the sequence with each item transformed by a selector function. Up to maxThreads transformations may happen in parallel.
-// (If maxThreads is zero, the number of CPUs is used.) Due to the parallelism, the items may be returned out of order.
-// If the selector is strongly typed, it will be called via reflection.
-func (s LINQ) ParallelSelectR(maxThreads int, selector T) LINQ {
-	return s.ParallelSelect(maxThreads, genericSelectorFunc(selector))
+// Implements ParallelSelectOrdered and ParallelSelectOrderedCtx, stopping the reading of new items from the source when the context
+// is done.
+func (s LINQ) parallelSelectOrdered(ctx context.Context, maxThreads, window int, selector Selector) LINQ {
+	if window == 0 {
+		window = maxThreads * 2
+	}
+	if maxThreads > window { // threads beyond the window size would never have anything to do
+		maxThreads = window
+	}
+	done := ctx.Done()
+
+	type result struct {
+		item, ex     T // the transformed item, or the value of the panic that occurred while reading or transforming it
+		ready, panic bool
+	}
+
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		i, m, srcM, wg := s.Iterator(), &sync.Mutex{}, &sync.Mutex{}, &sync.WaitGroup{}
+		changed := sync.NewCond(m) // signaled when a result becomes ready, space in the window frees up, or the iteration stops
+		// results is a ring buffer indexed by the items' positions in the source. reserved is the number of slots claimed by workers,
+		// and head is the position of the next item to return. eos is set when the source is exhausted, after which count is the
+		// number of items read from it. failed is set when reading or transforming an item panics. all of these are protected by m
+		results, reserved, head, count, started, eos, failed, closed := make([]result, window), 0, 0, 0, false, false, false, false
+		// the source has its own lock so that a slow read doesn't hold up the return of finished items. read is the number of items
+		// read from the source, and srcDone indicates that no more should be read. both are protected by srcM
+		read, srcDone := 0, false
+		readItem := func() (item T, r result, index int, ok bool) { // reads an item and its position from the source, catching any panic
+			srcM.Lock()
+			defer srcM.Unlock()
+			defer func() {
+				if e := recover(); e != nil {
+					r, index, ok = result{ex: e, ready: true, panic: true}, read, true
+					read, srcDone = read+1, true
+				}
+			}()
+			if !srcDone && !isDone(done) && i.Next() {
+				item, index = i.Current(), read
+				read++
+				return item, result{}, index, true
+			}
+			srcDone = true
+			return nil, result{}, read, false // at the end, return the number of items read
+		}
+		transform := func(item T) (r result) { // transforms an item, catching any panic
+			defer func() {
+				if e := recover(); e != nil {
+					r = result{ex: e, ready: true, panic: true}
+				}
+			}()
+			return result{item: selector(item), ready: true}
+		}
+		worker := func() {
+			defer wg.Done()
+			for {
+				m.Lock()
+				for reserved-head >= window && !closed && !eos && !failed { // wait until there's space in the window
+					changed.Wait()
+				}
+				if closed || eos || failed {
+					m.Unlock()
+					return
+				}
+				reserved++ // claim a slot in the window for the item we're about to read
+				m.Unlock()
+
+				item, r, index, ok := readItem()
+				if ok && !r.panic {
+					if r = transform(item); r.panic { // if the selector panicked, stop reading from the source
+						srcM.Lock()
+						srcDone = true
+						srcM.Unlock()
+					}
+				}
+				m.Lock()
+				if !ok { // if we reached the end of the source, index is the number of items read
+					eos, count = true, index
+				} else {
+					results[index%window] = r
+					failed = failed || r.panic // stop the workers if reading or transforming the item panicked
+				}
+				changed.Broadcast()
+				m.Unlock()
+			}
+		}
+		next := func() (T, bool, error) {
+			m.Lock()
+			defer m.Unlock()
+			if !started { // start the workers on the first call to Next
+				started = true
+				wg.Add(maxThreads)
+				for n := 0; n < maxThreads; n++ {
+					go worker()
+				}
+			}
+			for {
+				if r := results[head%window]; r.ready { // if the next item is ready, return it
+					results[head%window] = result{} // clear the slot so it can be reused and the item can be garbage-collected
+					head++
+					changed.Broadcast() // let the workers know there's space in the window
+					if r.panic {
+						panic(r.ex)
+					}
+					return r.item, true, nil
+				} else if eos && head == count { // otherwise, if we've returned everything, we're done
+					if err := ctx.Err(); err != nil { // if we stopped because the context is done, report that
+						return nil, false, err
+					}
+					return nil, false, IteratorError(i)
+				}
+				changed.Wait()
+			}
+		}
+		closeFunc := func() error { // stop reading from the source, wait for running goroutines to finish, and close the source
+			m.Lock()
+			closed = true
+			changed.Broadcast()
+			m.Unlock()
+			wg.Wait()
+			return CloseIterator(i)
+		}
+		return next, closeFunc
+	})
+}
+
+// Aggregates the items in the sequence in parallel with an aggregator function that is also used to combine the partial results,
+// and panics if the sequence is empty.
+func (s LINQ) mustParallelAggregate(threads int, agg Aggregator) T {
+	if result, ok := s.tryParallelAggregate(threads, nil, agg, agg); ok {
+		return result
+	}
+	panic(error(emptyError{}))
+}
+
+// Implements the parallel aggregates. If seedFactory is nil, the first item in each partition is used as its seed. Returns false if
+// the sequence is empty.
+func (s LINQ) tryParallelAggregate(threads int, seedFactory func() T, accumulate, combine Aggregator) (T, bool) {
+	type partial struct {
+		value T
+		ok    bool // whether the partial result has been initialized
+	}
+
+	// the goroutines started by ParallelForEach take partial results from the pool, so that each one is used by only one goroutine
+	// at a time. there's one for each thread, so a goroutine never has to wait for a partial result
+	threads = checkMaxThreads(threads)
+	pool := make(chan *partial, threads)
+	for n := 0; n < threads; n++ {
+		pool <- &partial{}
+	}
+	s.ParallelForEach(threads, func(item T) {
+		p := <-pool
+		defer func() { pool <- p }() // return the partial result to the pool even if a function panics
+		if p.ok {
+			p.value = accumulate(p.value, item)
+		} else if p.ok = true; seedFactory != nil {
+			p.value = accumulate(seedFactory(), item)
+		} else {
+			p.value = item
+		}
+	})
+	close(pool)
+
+	var result T
+	ok := false
+	for p := range pool { // combine the partial results
+		if p.ok {
+			if ok {
+				result = combine(result, p.value)
+			} else {
+				result, ok = p.value, true
+			}
+		}
+	}
+	return result, ok
+}
+
+// The result of evaluating a predicate in parallel, used by ParallelWhere.
+type filterResult struct {
+	item T
+	keep bool
+}
+
+// Returns a selector that evaluates a predicate and returns a filterResult.
+func filterSelector(pred Predicate) Selector {
+	return func(item T) T { return filterResult{item, pred(item)} }
+}
+
+// Returns the items from a sequence of filterResults that should be kept.
+func filterItems(results LINQ) LINQ {
+	return results.pipe(func(i Iterator) IteratorFunc {
+		return func() (T, bool) {
+			for i.Next() {
+				if r := i.Current().(filterResult); r.keep {
+					return r.item, true
+				}
+			}
+			return nil, false
+		}
+	})
+}
+
+// A selector that returns the item unchanged, which is expected to be a sequence or nil.
+func selectSequence(seq T) T {
+	return seq
 }
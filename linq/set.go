@@ -21,16 +21,25 @@ package linq
 
 import . "github.com/AdamMil/go/collections"
 
-// Returns the sequence without duplicates (using go's rules for the equality of map keys). Order is preserved, so
+// Returns the sequence without duplicates (comparing items with GenericEqual). Order is preserved, so
 // the first of item in each set of duplicates will be included in the resulting sequence.
 func (s LINQ) Distinct() LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
-		iter, set := s.Iterator(), set{}
+	return s.DistinctP(nil, nil)
+}
+
+// Returns the sequence without duplicates, comparing items with the given equality function and hashing them with the given hash
+// function, which must return the same hash code for equal items. For example, passing DeepEqual and DeepHash compares items by
+// their contents. If cmp is nil, GenericEqual and GenericHash are used. If hash is nil, all items get the same hash code, which works
+// but takes time proportional to the square of the number of distinct items. Order is preserved, so the first of item in each set
+// of duplicates will be included in the resulting sequence.
+func (s LINQ) DistinctP(cmp EqualFunc, hash func(T) uint64) LINQ {
+	return s.pipe(func(iter Iterator) IteratorFunc {
+		set := MakeHashSetWithComparer(0, cmp, hash)
 		return func() (T, bool) {
 			for {
 				if !iter.Next() { // if we're at the end, we're done
 					return nil, false
-				} else if item := iter.Current(); set.tryAdd(item) { // if the item didn't exist in the set, return it
+				} else if item := iter.Current(); set.Add(item) { // if the item didn't exist in the set, return it
 					return item, true
 				} // otherwise, advance to the next item
 			}
@@ -38,7 +47,7 @@ func (s LINQ) Distinct() LINQ {
 	})
 }
 
-// Returns the sequence without the items from any of the given sequences (using go's rules for the equality of map keys).
+// Returns the sequence without the items from any of the given sequences (comparing items with GenericEqual).
 // The order of items in the receiver sequence is preserved.
 func (s LINQ) Except(sequences ...Sequence) LINQ {
 	if len(sequences) == 0 {
@@ -50,42 +59,50 @@ func (s LINQ) Except(sequences ...Sequence) LINQ {
 		except = concatSequence(except, sequences[1:])
 	}
 
-	var set set
-	return FromSequenceFunction(func() IteratorFunc {
+	var set *HashSet
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
 		iter := s.Iterator()
-		return func() (T, bool) {
+		next := func() (T, bool, error) {
 			if set == nil { // on the first call to Next, convert the except sequence into a set
-				set = toSet(except)
+				var err error
+				if set, err = tryMakeHashSet(except); err != nil {
+					return nil, false, err
+				}
 			}
 			for {
 				if !iter.Next() { // if we're at the end, we're done
-					return nil, false
-				} else if item := iter.Current(); !set.contains(item) { // if the current item isn't in the set, return it
-					return item, true
+					return nil, false, IteratorError(iter)
+				} else if item := iter.Current(); !set.Contains(item) { // if the current item isn't in the set, return it
+					return item, true, nil
 				} // otherwise, skip it and move to the next item
 			}
 		}
+		return next, func() error { return CloseIterator(iter) }
 	})
 }
 
-// Returns the sequence with only the items that also exist in the given sequence (using go's rules for the equality of map keys).
+// Returns the sequence with only the items that also exist in the given sequence (comparing items with GenericEqual).
 // Duplicates will also be removed. The order of items in the receiver sequence is preserved.
 func (s LINQ) Intersect(seq Sequence) LINQ {
-	var rset set
-	return FromSequenceFunction(func() IteratorFunc {
-		iter, lset := s.Iterator(), set{}
-		return func() (T, bool) {
+	var rset *HashSet
+	return FromFallibleSequenceFunction(func() (FallibleIteratorFunc, func() error) {
+		iter, lset := s.Iterator(), MakeHashSet(0)
+		next := func() (T, bool, error) {
 			if rset == nil {
-				rset = toSet(seq)
+				var err error
+				if rset, err = tryMakeHashSet(seq); err != nil {
+					return nil, false, err
+				}
 			}
 			for {
 				if !iter.Next() {
-					return nil, false
-				} else if item := iter.Current(); rset.contains(item) && lset.tryAdd(item) {
-					return item, true
+					return nil, false, IteratorError(iter)
+				} else if item := iter.Current(); rset.Contains(item) && lset.Add(item) {
+					return item, true, nil
 				}
 			}
 		}
+		return next, func() error { return CloseIterator(iter) }
 	})
 }
 
@@ -100,25 +117,11 @@ func (s LINQ) Union(sequences ...Sequence) LINQ {
 	}
 }
 
-type set map[T]T
-
-func (s set) contains(key T) bool {
-	_, ok := s[key]
-	return ok
-}
-
-func (s set) tryAdd(key T) bool {
-	_, exists := s[key]
-	if !exists {
-		s[key] = nil
-	}
-	return !exists
-}
-
-func toSet(s Sequence) set {
-	m := make(map[T]T)
-	for i := s.Iterator(); i.Next(); {
-		m[i.Current()] = nil
+// Creates a HashSet from the items in a sequence, or returns the error that ended the iteration or from closing the iterator.
+func tryMakeHashSet(seq Sequence) (*HashSet, error) {
+	set := MakeHashSet(0)
+	if err := (LINQ{seq}).TryForEach(func(item T) { set.Add(item) }); err != nil {
+		return nil, err
 	}
-	return set(m)
+	return set, nil
 }
@@ -29,8 +29,8 @@ func (s LINQ) Skip(n int) LINQ {
 	} else if n < 0 {
 		panic("argument must be non-negative")
 	}
-	return FromSequenceFunction(func() IteratorFunc {
-		i, skipped := s.Iterator(), false
+	return s.pipe(func(i Iterator) IteratorFunc {
+		skipped := false
 		return func() (T, bool) {
 			if !skipped {
 				for count := 0; count < n && i.Next(); count++ {
@@ -47,8 +47,8 @@ func (s LINQ) Skip(n int) LINQ {
 
 // Returns the sequence with the all items matching the given predicate removed from the front.
 func (s LINQ) SkipWhile(pred Predicate) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
-		i, skipped := s.Iterator(), false
+	return s.pipe(func(i Iterator) IteratorFunc {
+		skipped := false
 		return func() (T, bool) {
 			for {
 				if !i.Next() {
@@ -76,8 +76,8 @@ func (s LINQ) Take(n int) LINQ {
 	} else if n < 0 {
 		panic("argument must be non-negative")
 	}
-	return FromSequenceFunction(func() IteratorFunc {
-		i, count := s.Iterator(), 0
+	return s.pipe(func(i Iterator) IteratorFunc {
+		count := 0
 		return func() (T, bool) {
 			if count < n && i.Next() {
 				count++
@@ -90,8 +90,8 @@ func (s LINQ) Take(n int) LINQ {
 
 // Returns the items from the sequence, excluding the first item that doesn't match the predicate and all subsequent items.
 func (s LINQ) TakeWhile(pred Predicate) LINQ {
-	return FromSequenceFunction(func() IteratorFunc {
-		i, done := s.Iterator(), false
+	return s.pipe(func(i Iterator) IteratorFunc {
+		done := false
 		return func() (T, bool) {
 			for {
 				if done || !i.Next() {